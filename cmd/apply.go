@@ -0,0 +1,73 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply plan.json",
+	Short: "Execute the actions of a plan",
+	Long: `Execute exactly the actions of a plan written by the plan command.
+
+Actions whose source file changed in size, modification time or inode
+since planning are refused and reported as failed.
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkReportFlags()
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error reading plan")
+		}
+		plan, err := pkg.ReadPlan(file)
+		_ = file.Close()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"file":  args[0],
+			}).Fatal("error parsing plan")
+		}
+
+		exitWithResult(pkg.Apply(plan))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// applyCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// applyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	applyCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Write a record of every file action as json, yaml, csv or table.")
+	applyCmd.Flags().StringVar(&reportFile, "report-file", "", "Write the records into this file instead of stdout, as json by default.")
+}
@@ -37,7 +37,8 @@ cleanup:
       - ".LSOverride"
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkg.Cleanup(dryRunCleanup)
+		checkReportFlags()
+		exitWithResult(pkg.Cleanup(dryRunCleanup))
 	},
 }
 
@@ -55,4 +56,6 @@ func init() {
 	// cleanupCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	cleanupCmd.Flags().BoolVarP(&dryRunCleanup, "dry-run", "d", false, "Do not actually delete anything.")
+	cleanupCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Write a record of every file action as json, yaml, csv or table.")
+	cleanupCmd.Flags().StringVar(&reportFile, "report-file", "", "Write the records into this file instead of stdout, as json by default.")
 }
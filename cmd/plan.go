@@ -0,0 +1,97 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+var outPlan string
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Write all actions of relocate and cleanup rules into a plan",
+	Long: `Compute every move, copy and removal relocate and cleanup rules would
+perform without changing anything and write them into a plan file.
+
+Review the plan and execute exactly these actions with:
+
+  brot plan --out plan.json
+  brot apply plan.json
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		plan, result := pkg.NewPlan()
+
+		summary := io.Writer(os.Stdout)
+		if outPlan == "" {
+			// keep stdout for the plan only
+			summary = os.Stderr
+			if err := pkg.WritePlan(os.Stdout, plan); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("error writing plan")
+			}
+		} else if err := writePlanFile(plan); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"file":  outPlan,
+			}).Fatal("error writing plan")
+		}
+
+		printSummary(summary, result)
+		printErrors(os.Stderr, result)
+
+		if code := result.ExitCode(); code != pkg.ExitSuccess {
+			os.Exit(code)
+		}
+	},
+}
+
+// writePlanFile writes the plan into the file given by --out
+func writePlanFile(plan pkg.Plan) error {
+	file, err := os.Create(outPlan)
+	if err != nil {
+		return err
+	}
+	if err := pkg.WritePlan(file, plan); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// planCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// planCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	planCmd.Flags().StringVar(&outPlan, "out", "", "Write the plan into this file instead of stdout.")
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+var restoreAllQuarantine bool = false
+
+var olderThanQuarantine string = "30d"
+
+// quarantineCmd represents the quarantine command
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "Manage files quarantined by cleanup rules",
+	Long: `List, restore or permanently delete files moved into quarantine by
+cleanup rules with mode quarantine.
+
+cleanup:
+  - name: old logs
+    src: $HOME/logs
+    patterns:
+      - "*.log"
+    mode: quarantine
+	`,
+}
+
+// quarantineListCmd represents the quarantine list command
+var quarantineListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List quarantined files",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := pkg.QuarantineList()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error reading quarantine")
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "ID\tTIME\tRULE\tPATH")
+		for _, entry := range entries {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", entry.ID, entry.Time.Format(time.DateTime), entry.Rule, entry.Path)
+		}
+		_ = writer.Flush()
+	},
+}
+
+// quarantineRestoreCmd represents the quarantine restore command
+var quarantineRestoreCmd = &cobra.Command{
+	Use:   "restore [id...]",
+	Short: "Move quarantined files back to their original location",
+	Run: func(cmd *cobra.Command, args []string) {
+		ids := args
+		if restoreAllQuarantine {
+			entries, err := pkg.QuarantineList()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("error reading quarantine")
+			}
+			ids = nil
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+		}
+
+		if err := pkg.QuarantineRestore(ids); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error restoring files")
+		}
+	},
+}
+
+// quarantinePurgeCmd represents the quarantine purge command
+var quarantinePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete quarantined files",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		purged, err := pkg.QuarantinePurge(olderThanQuarantine)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error purging files")
+		}
+
+		log.WithFields(log.Fields{
+			"files": purged,
+		}).Info("purged quarantine")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(quarantineCmd)
+	quarantineCmd.AddCommand(quarantineListCmd)
+	quarantineCmd.AddCommand(quarantineRestoreCmd)
+	quarantineCmd.AddCommand(quarantinePurgeCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// quarantineCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// quarantineCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	quarantineRestoreCmd.Flags().BoolVarP(&restoreAllQuarantine, "all", "a", false, "Restore all quarantined files.")
+	quarantinePurgeCmd.Flags().StringVar(&olderThanQuarantine, "older-than", "30d", "Only delete files quarantined before this duration, e.g. 14d.")
+}
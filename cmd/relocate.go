@@ -37,7 +37,8 @@ relocate:
     mode: move
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkg.Relocate(dryRunRelocate)
+		checkReportFlags()
+		exitWithResult(pkg.Relocate(dryRunRelocate))
 	},
 }
 
@@ -55,4 +56,6 @@ func init() {
 	// relocateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	relocateCmd.Flags().BoolVarP(&dryRunRelocate, "dry-run", "d", false, "Do not actually move/copy anything.")
+	relocateCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Write a record of every file action as json, yaml, csv or table.")
+	relocateCmd.Flags().StringVar(&reportFile, "report-file", "", "Write the records into this file instead of stdout, as json by default.")
 }
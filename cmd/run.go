@@ -0,0 +1,69 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+var dryRunRun bool = false
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Apply relocate and cleanup rules in one pass",
+	Long: `Apply all relocate and cleanup rules in the configured order. Every
+source directory is scanned only once and files handled by an earlier
+rule are skipped by all following rules.
+
+defaults:
+  order:
+    - relocate
+    - cleanup
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkReportFlags()
+
+		result, err := pkg.Run(dryRunRun)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error ordering rules")
+		}
+		exitWithResult(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// runCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// runCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	runCmd.Flags().BoolVarP(&dryRunRun, "dry-run", "d", false, "Do not actually move/copy/remove anything.")
+	runCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Write a record of every file action as json, yaml, csv or table.")
+	runCmd.Flags().StringVar(&reportFile, "report-file", "", "Write the records into this file instead of stdout, as json by default.")
+}
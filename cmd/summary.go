@@ -0,0 +1,106 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+)
+
+// format of the records written for every file action, no records are written if empty
+var outputFormat string
+
+// file to write the records into instead of stdout
+var reportFile string
+
+// checkReportFlags aborts on an invalid report format before any rule is applied
+func checkReportFlags() {
+	if outputFormat != "" && !pkg.IsReportFormat(outputFormat) {
+		log.WithFields(log.Fields{
+			"output": outputFormat,
+		}).Fatal("invalid output format")
+	}
+}
+
+// writeReportFile writes the records of the run into the report file
+func writeReportFile(format string, records []pkg.Record) error {
+	file, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	if err := pkg.WriteReport(file, format, records); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// printSummary writes the counts of every rule as a table
+func printSummary(w io.Writer, result pkg.Result) {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "RULE\tMATCHED\tMOVED\tCOPIED\tREMOVED\tSKIPPED\tFAILED")
+	for _, rule := range result.Rules {
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			rule.Name, rule.Matched, rule.Moved, rule.Copied, rule.Removed, rule.Skipped, rule.Failed)
+	}
+	_ = writer.Flush()
+}
+
+// printErrors writes every error of the run on its own line
+func printErrors(w io.Writer, result pkg.Result) {
+	for _, err := range result.Errors() {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+	}
+}
+
+// exitWithResult writes the report, prints the summary and exits with a non-zero code if any rule failed
+func exitWithResult(result pkg.Result) {
+	summary := io.Writer(os.Stdout)
+
+	switch {
+	case reportFile != "":
+		format := outputFormat
+		if format == "" {
+			format = pkg.ReportJSON
+		}
+		if err := writeReportFile(format, result.Records()); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"file":  reportFile,
+			}).Fatal("error writing report")
+		}
+	case outputFormat != "":
+		// keep stdout for the records only
+		summary = os.Stderr
+		if err := pkg.WriteReport(os.Stdout, outputFormat, result.Records()); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error writing report")
+		}
+	}
+
+	printSummary(summary, result)
+	printErrors(os.Stderr, result)
+
+	if code := result.ExitCode(); code != pkg.ExitSuccess {
+		os.Exit(code)
+	}
+}
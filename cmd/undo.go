@@ -0,0 +1,94 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+var dryRunUndo bool = false
+
+var listUndo bool = false
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [run-id]",
+	Short: "Reverse the changes of a previous run",
+	Long: `Reverse all moves, copies, trashed and quarantined files of a previous
+run recorded in the journal. Without a run id the latest run which was not
+undone yet is reversed. Removed files cannot be restored.
+
+List all recorded runs with:
+
+  brot undo --list
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if listUndo {
+			runs, err := pkg.JournalRuns()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Fatal("error reading journal")
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "RUN\tTIME\tCHANGES\tUNDONE")
+			for _, run := range runs {
+				_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t%t\n", run.ID, run.Time.Format(time.DateTime), run.Entries, run.Undone)
+			}
+			_ = writer.Flush()
+			return
+		}
+
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+
+		result, err := pkg.Undo(id, dryRunUndo)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error undoing run")
+		}
+		exitWithResult(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// undoCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// undoCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	undoCmd.Flags().BoolVarP(&dryRunUndo, "dry-run", "d", false, "Do not actually reverse anything.")
+	undoCmd.Flags().BoolVarP(&listUndo, "list", "l", false, "List all runs recorded in the journal.")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/siwei-luo/brot/pkg"
+	"github.com/spf13/cobra"
+)
+
+var dryRunWatch bool = false
+
+var delayWatch time.Duration = 2 * time.Second
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Apply relocate and cleanup rules continuously",
+	Long: `Keep running and apply relocate and cleanup rules as soon as matching
+files appear in the source directory of a rule.
+
+Stop watching with SIGINT (Ctrl+C) or SIGTERM.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := pkg.Watch(ctx, dryRunWatch, delayWatch); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("error watching directories")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// watchCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// watchCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	watchCmd.Flags().BoolVarP(&dryRunWatch, "dry-run", "d", false, "Do not actually move/copy/delete anything.")
+	watchCmd.Flags().DurationVar(&delayWatch, "delay", 2*time.Second, "Wait for this long after the last change before applying rules.")
+}
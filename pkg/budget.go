@@ -0,0 +1,140 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sizeBudget limits the disk usage of the files matched by a cleanup rule
+type sizeBudget struct {
+	maxTotalSize int64
+	minFreeSpace int64
+}
+
+// budgetFile is a matched file with the values needed to enforce the budget
+type budgetFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// budget parses the size budget options of a cleanup rule
+func (item cleanupRule) budget() (sizeBudget, error) {
+	budget := sizeBudget{maxTotalSize: -1, minFreeSpace: -1}
+
+	var err error
+	if item.MaxTotalSize != "" {
+		if budget.maxTotalSize, err = parseSize(item.MaxTotalSize); err != nil {
+			return budget, fmt.Errorf("invalid maxTotalSize: %w", err)
+		}
+	}
+	if item.MinFreeSpace != "" {
+		if budget.minFreeSpace, err = parseSize(item.MinFreeSpace); err != nil {
+			return budget, fmt.Errorf("invalid minFreeSpace: %w", err)
+		}
+		// trashed and quarantined files usually stay on the same filesystem and free no space at all
+		if item.Mode != "" && item.Mode != cleanupRemove {
+			return budget, fmt.Errorf("invalid minFreeSpace: mode %q frees no space", item.Mode)
+		}
+		// fail early on platforms without support
+		if _, err := freeSpace(os.TempDir()); err != nil {
+			return budget, fmt.Errorf("minFreeSpace not supported: %w", err)
+		}
+	}
+
+	return budget, nil
+}
+
+// enabled reports whether the budget limits anything at all
+func (b sizeBudget) enabled() bool {
+	return b.maxTotalSize >= 0 || b.minFreeSpace >= 0
+}
+
+// apply returns the oldest of the candidates which have to be cleaned up to satisfy the budget
+//
+// The total size is calculated from all matched files, while only candidates are cleaned up.
+func (b sizeBudget) apply(directory string, matched []string, candidates []string) ([]string, error) {
+	if !b.enabled() {
+		return candidates, nil
+	}
+
+	var total int64
+	sizes := map[string]budgetFile{}
+	for _, path := range matched {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		size := pathSize(path, info)
+		total += size
+		sizes[path] = budgetFile{path: path, size: size, modTime: info.ModTime()}
+	}
+
+	free := int64(-1)
+	if b.minFreeSpace >= 0 {
+		var err error
+		if free, err = freeSpace(directory); err != nil {
+			return nil, fmt.Errorf("error reading free space: %w", err)
+		}
+	}
+
+	// oldest files first
+	var files []budgetFile
+	for _, path := range candidates {
+		if file, ok := sizes[path]; ok {
+			files = append(files, file)
+		}
+	}
+	slices.SortStableFunc(files, func(a, b budgetFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	var obsolete []string
+	for _, file := range files {
+		if b.satisfied(total, free) {
+			break
+		}
+		obsolete = append(obsolete, file.path)
+		total -= file.size
+		if free >= 0 {
+			free += file.size
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"files": len(obsolete),
+		"total": total,
+	}).Debug("enforce budget")
+
+	return obsolete, nil
+}
+
+// satisfied reports whether the total size and free space are within the budget
+func (b sizeBudget) satisfied(total int64, free int64) bool {
+	if b.maxTotalSize >= 0 && total > b.maxTotalSize {
+		return false
+	}
+	if b.minFreeSpace >= 0 && free < b.minFreeSpace {
+		return false
+	}
+	return true
+}
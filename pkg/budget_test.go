@@ -0,0 +1,103 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSizeBudgetMaxTotalSize(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// four files with 8 bytes each, test_1.txt is the oldest
+	var files []string
+	for i := 1; i <= 4; i++ {
+		file := filepath.Join(testDir, "src", "test_"+string(rune(48+i))+".txt")
+		modTime := time.Now().Add(time.Duration(i-10) * time.Hour)
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Errorf("error - setting modification time: %v", err)
+		}
+		files = append(files, file)
+	}
+
+	budget, err := cleanupRule{MaxTotalSize: "20B"}.budget()
+	if err != nil {
+		t.Errorf("error - parsing budget: %v", err)
+	}
+
+	// 32 bytes in total, the two oldest files have to go
+	obsolete, _ := budget.apply(testDir, files, files)
+	if len(obsolete) != 2 || obsolete[0] != files[0] || obsolete[1] != files[1] {
+		t.Errorf("failed - got %q but expected the two oldest files", obsolete)
+	}
+
+	// retained files count towards the budget but are never cleaned up
+	obsolete, _ = budget.apply(testDir, files, files[1:])
+	if len(obsolete) != 2 || obsolete[0] != files[1] || obsolete[1] != files[2] {
+		t.Errorf("failed - got %q but expected the oldest candidates", obsolete)
+	}
+
+	// nothing to do within the budget
+	budget, _ = cleanupRule{MaxTotalSize: "1KB"}.budget()
+	if obsolete, _ = budget.apply(testDir, files, files); len(obsolete) != 0 {
+		t.Errorf("failed - got %q but expected no files", obsolete)
+	}
+
+	// without budget all candidates are cleaned up
+	budget, _ = cleanupRule{}.budget()
+	if obsolete, _ = budget.apply(testDir, files, files); len(obsolete) != len(files) {
+		t.Errorf("failed - got %q but expected all files", obsolete)
+	}
+}
+
+func TestSizeBudgetMinFreeSpace(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	if _, err := freeSpace(testDir); err != nil {
+		t.Skipf("free space not supported: %v", err)
+	}
+
+	files := FilesFromDirectory(testDir, []string{"*.txt"})
+
+	// no filesystem has that much free space, so everything has to go
+	budget, err := cleanupRule{MinFreeSpace: "1000000TiB"}.budget()
+	if err != nil {
+		t.Errorf("error - parsing budget: %v", err)
+	}
+	if obsolete, _ := budget.apply(testDir, files, files); len(obsolete) != len(files) {
+		t.Errorf("failed - got %q but expected all files", obsolete)
+	}
+
+	// any filesystem has that much free space
+	budget, _ = cleanupRule{MinFreeSpace: "0"}.budget()
+	if obsolete, _ := budget.apply(testDir, files, files); len(obsolete) != 0 {
+		t.Errorf("failed - got %q but expected no files", obsolete)
+	}
+}
+
+func TestSizeBudgetInvalid(t *testing.T) {
+	for _, rule := range []cleanupRule{{MaxTotalSize: "lots"}, {MinFreeSpace: "-"}, {MinFreeSpace: "1GiB", Mode: cleanupTrash}} {
+		if _, err := rule.budget(); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("failed - expected error for %+v", rule)
+		}
+	}
+}
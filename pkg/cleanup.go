@@ -16,46 +16,182 @@ limitations under the License.
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func Cleanup(dryRun bool) {
+func Cleanup(dryRun bool) Result {
+	var result Result
+
 	// log used configuration file
 	log.Info("use config file: ", viper.ConfigFileUsed())
 
 	// iterate over cleanup definitions from configuration
+	executor := newExecutor(dryRun)
 	for _, item := range CurrentConfiguration.Cleanup {
+		result.Rules = append(result.Rules, cleanupItem(item, executor))
+	}
 
-		// expand any environment variables
-		srcDirectory := os.ExpandEnv(item.Source)
+	return result
+}
 
-		// get files from source directory
-		cleanupFiles := FilesFromDirectory(srcDirectory, item.Patterns)
+// modes of cleanup rules
+const (
+	cleanupRemove     = "remove"
+	cleanupTrash      = "trash"
+	cleanupQuarantine = "quarantine"
+)
 
-		// skip item if there are no files to cleanup
-		if cleanupFiles == nil {
-			continue
-		}
+// cleanupItem removes all files matched by a single cleanup rule
+func cleanupItem(item cleanupRule, executor *executor) (result RuleResult) {
+	result.Name = item.Name
+	result.dryRun = executor.dryRun
 
-		for _, srcPath := range cleanupFiles {
+	entries, ok := planCleanupItem(item, executor, &result)
+	if !ok {
+		return
+	}
+
+	for _, entry := range entries {
+		executor.execute(entry, &result)
+	}
 
-			if !dryRun {
-				if err := FileRemove(srcPath); err != nil {
-					log.WithFields(log.Fields{
-						"error": err,
-						"src":   srcPath,
-					}).Error("error removing file")
-					continue
-				}
+	// remove all empty directories below the source directory, a dry run assumes all planned files are gone
+	if item.PruneEmptyDirs {
+		removed := map[string]bool{}
+		if executor.dryRun {
+			for _, entry := range entries {
+				removed[entry.Src] = true
 			}
+		}
+		for _, entry := range planPrune(item, removed) {
+			executor.execute(entry, &result)
+		}
+	}
+
+	return
+}
+
+// planCleanupItem selects all files to clean up for a single cleanup rule, invalid rules are recorded in result
+func planCleanupItem(item cleanupRule, executor *executor, result *RuleResult) ([]PlanEntry, bool) {
+	// expand any environment variables
+	srcDirectory := os.ExpandEnv(item.Source)
+
+	// check for a valid mode, files are removed by default
+	mode := item.Mode
+	if mode == "" {
+		mode = cleanupRemove
+	}
+	if mode != cleanupRemove && mode != cleanupTrash && mode != cleanupQuarantine {
+		log.WithFields(log.Fields{
+			"mode": item.Mode,
+		}).Error("skip invalid cleanup mode")
+		result.invalid(fmt.Errorf("invalid cleanup mode: %q", item.Mode))
+		return nil, false
+	}
+
+	// parse the conditions to select files
+	filter, err := item.compile()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return nil, false
+	}
+
+	// parse which files are kept
+	retention, err := item.retention()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return nil, false
+	}
+
+	// parse the disk usage limits
+	budget, err := item.budget()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return nil, false
+	}
+
+	// get files from source directory
+	matchedFiles := executor.files(srcDirectory, filter)
+
+	// drop all files which are kept and only clean up as much as needed to satisfy the budget
+	cleanupFiles := retention.apply(matchedFiles)
+	cleanupFiles, err = budget.apply(srcDirectory, matchedFiles, cleanupFiles)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return nil, false
+	}
+
+	result.Matched = len(matchedFiles)
+
+	// record all files which are kept
+	obsolete := make(map[string]bool, len(cleanupFiles))
+	for _, srcPath := range cleanupFiles {
+		obsolete[srcPath] = true
+	}
+	for _, srcPath := range matchedFiles {
+		if !obsolete[srcPath] {
+			result.skip(result.newRecord(srcPath, mode))
+		}
+	}
 
+	var entries []PlanEntry
+	for _, srcPath := range cleanupFiles {
+		info, err := os.Lstat(srcPath)
+		if errors.Is(err, os.ErrNotExist) {
+			// the file was handled by someone else since listing it
 			log.WithFields(log.Fields{
 				"src": srcPath,
-			}).Infof("remove file: %v", srcPath)
+			}).Debug("skip vanished file")
+			result.skip(result.newRecord(srcPath, mode))
+			continue
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"src":   srcPath,
+			}).Error("error reading file")
+			result.fail(result.newRecord(srcPath, mode), err)
+			continue
+		}
+
+		entry := newPlanEntry(item.Name, mode, srcPath, info)
 
+		// remove directories which only contained the file
+		if item.RemoveEmptyDirs {
+			entry.Root = srcDirectory
 		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, true
+}
+
+// planPrune lists all empty directories below the source directory of a cleanup rule, deepest first
+func planPrune(item cleanupRule, removed map[string]bool) []PlanEntry {
+	var entries []PlanEntry
+	for _, directory := range emptyDirectories(os.ExpandEnv(item.Source), removed) {
+		entries = append(entries, PlanEntry{Rule: item.Name, Action: actionPrune, Src: directory})
 	}
+	return entries
 }
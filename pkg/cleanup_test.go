@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func initCleanupTestDirectory(t *testing.T) string {
@@ -38,11 +39,7 @@ func initCleanupTestDirectory(t *testing.T) string {
 }
 
 func setupCleanupConfig(srcDir string, patterns []string) {
-	CurrentConfiguration.Cleanup = make([]struct {
-		Name     string   `mapstructure:"name"`
-		Source   string   `mapstructure:"src"`
-		Patterns []string `mapstructure:"patterns"`
-	}, 1)
+	CurrentConfiguration.Cleanup = make([]cleanupRule, 1)
 	CurrentConfiguration.Cleanup[0].Name = "test-cleanup"
 	CurrentConfiguration.Cleanup[0].Source = srcDir
 	CurrentConfiguration.Cleanup[0].Patterns = patterns
@@ -165,11 +162,7 @@ func TestCleanupEnvironmentVariables(t *testing.T) {
 	defer os.Unsetenv(testEnvVar)
 
 	// setup configuration with environment variable in path
-	CurrentConfiguration.Cleanup = make([]struct {
-		Name     string   `mapstructure:"name"`
-		Source   string   `mapstructure:"src"`
-		Patterns []string `mapstructure:"patterns"`
-	}, 1)
+	CurrentConfiguration.Cleanup = make([]cleanupRule, 1)
 	CurrentConfiguration.Cleanup[0].Name = "test-env-vars"
 	CurrentConfiguration.Cleanup[0].Source = "$" + testEnvVar
 	CurrentConfiguration.Cleanup[0].Patterns = []string{"file_1.txt"}
@@ -232,11 +225,7 @@ func TestCleanupMultiplePatterns(t *testing.T) {
 	srcDir := filepath.Join(testDir, "src")
 
 	// setup configuration with multiple patterns
-	CurrentConfiguration.Cleanup = make([]struct {
-		Name     string   `mapstructure:"name"`
-		Source   string   `mapstructure:"src"`
-		Patterns []string `mapstructure:"patterns"`
-	}, 1)
+	CurrentConfiguration.Cleanup = make([]cleanupRule, 1)
 	CurrentConfiguration.Cleanup[0].Name = "test-multi-pattern"
 	CurrentConfiguration.Cleanup[0].Source = srcDir
 	CurrentConfiguration.Cleanup[0].Patterns = []string{"file_1.txt", "file_2.txt", "keep_this.txt"}
@@ -289,3 +278,219 @@ func TestCleanupEmptyDirectory(t *testing.T) {
 	}
 }
 
+func TestCleanupOlderThan(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	// make a single file old enough
+	oldFile := filepath.Join(srcDir, "file_1.txt")
+	old := time.Now().Add(-15 * 24 * time.Hour)
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Cleanup[0].OlderThan = "14d"
+	Cleanup(false)
+
+	// verify only the old file is removed
+	if _, err := os.Stat(oldFile); err == nil {
+		t.Errorf("failed - old file should be removed: %q", oldFile)
+	}
+	file2 := filepath.Join(srcDir, "file_2.txt")
+	if _, err := os.Stat(file2); err != nil {
+		t.Errorf("failed - recent file should still exist: %q", file2)
+	}
+}
+
+func TestCleanupInvalidRule(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Cleanup[0].MaxSize = "huge"
+	Cleanup(false)
+
+	// verify the invalid rule did not remove anything
+	file := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("failed - file should still exist for invalid rule: %q", file)
+	}
+}
+
+func TestCleanupExclude(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Cleanup[0].Exclude = []string{"keep_*.txt"}
+	Cleanup(false)
+
+	// verify excluded file still exists
+	keepFile := filepath.Join(srcDir, "keep_this.txt")
+	if _, err := os.Stat(keepFile); err != nil {
+		t.Errorf("failed - excluded file should still exist: %q", keepFile)
+	}
+
+	// verify other files are removed
+	file := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("failed - file should be removed: %q", file)
+	}
+}
+
+func TestCleanupTrashMode(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testDir, "share"))
+
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = "trash"
+	Cleanup(false)
+
+	// verify the file was moved into the trash
+	file := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("failed - file should be trashed: %q", file)
+	}
+	trashed := filepath.Join(testDir, "share", "Trash", "files", "file_1.txt")
+	if _, err := os.Stat(trashed); err != nil {
+		t.Errorf("failed - trashed file missing: %q", trashed)
+	}
+}
+
+func TestCleanupInvalidMode(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = "shred"
+	Cleanup(false)
+
+	// verify the invalid rule did not remove anything
+	file := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("failed - file should still exist for invalid mode: %q", file)
+	}
+}
+
+func TestCleanupQuarantineMode(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	setupQuarantineConfig(t, testDir)
+
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = "quarantine"
+	Cleanup(false)
+
+	// verify the file was moved into quarantine
+	file := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("failed - file should be quarantined: %q", file)
+	}
+	entries, err := QuarantineList()
+	if err != nil || len(entries) != 1 || entries[0].Rule != "test-cleanup" {
+		t.Errorf("failed - unexpected quarantine entries: %v, %v", entries, err)
+	}
+}
+
+func TestCleanupSkipsInternalFiles(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	setupQuarantineConfig(t, filepath.Join(srcDir, "brot"))
+
+	// a trash directory of the volume is never cleaned up
+	createTestDir(t, filepath.Join(srcDir, ".Trash-1000"))
+	createTestFile(t, filepath.Join(srcDir, ".Trash-1000", "file_4.txt"))
+
+	setupCleanupConfig(srcDir, []string{"file_1.txt", "file_4.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = cleanupQuarantine
+	if result := Cleanup(false); result.Rules[0].Matched != 1 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	// the quarantine directory below the source directory is not matched again
+	if result := Cleanup(false); result.Rules[0].Matched != 0 {
+		t.Errorf("failed - quarantined file matched again: %+v", result)
+	}
+	entries, err := QuarantineList()
+	if err != nil || len(entries) != 1 || entries[0].Path != filepath.Join(srcDir, "file_1.txt") {
+		t.Errorf("failed - unexpected quarantine entries: %v, %v", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, ".Trash-1000", "file_4.txt")); err != nil {
+		t.Errorf("failed - file in trash directory should not be touched: %v", err)
+	}
+}
+
+func TestCleanupKeep(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	// make file_3.txt the newest file
+	for i := 1; i <= 3; i++ {
+		file := filepath.Join(srcDir, "file_"+string(rune(48+i))+".txt")
+		modTime := time.Now().Add(time.Duration(i-4) * time.Hour)
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Errorf("error - setting modification time: %v", err)
+		}
+	}
+
+	setupCleanupConfig(srcDir, []string{"file_*.txt"})
+	CurrentConfiguration.Cleanup[0].Keep = 1
+	Cleanup(false)
+
+	// verify only the newest file is kept
+	for i := 1; i <= 2; i++ {
+		file := filepath.Join(srcDir, "file_"+string(rune(48+i))+".txt")
+		if _, err := os.Stat(file); err == nil {
+			t.Errorf("failed - file should be removed: %q", file)
+		}
+	}
+	newest := filepath.Join(srcDir, "file_3.txt")
+	if _, err := os.Stat(newest); err != nil {
+		t.Errorf("failed - newest file should still exist: %q", newest)
+	}
+}
+
+func TestCleanupMaxTotalSize(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	// make file_1.txt the oldest file
+	oldFile := filepath.Join(srcDir, "file_1.txt")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	// four files with 8 bytes each
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Cleanup[0].MaxTotalSize = "24"
+	Cleanup(false)
+
+	// verify only the oldest file was removed
+	if _, err := os.Stat(oldFile); err == nil {
+		t.Errorf("failed - oldest file should be removed: %q", oldFile)
+	}
+	if files := FilesFromDirectory(srcDir, []string{"*.txt"}); len(files) != 3 {
+		t.Errorf("failed - got %q but expected %d remaining files", files, 3)
+	}
+}
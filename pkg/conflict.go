@@ -0,0 +1,160 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// strategies to resolve a file with the same name in the destination
+const (
+	conflictSkip             = "skip"
+	conflictOverwrite        = "overwrite"
+	conflictOverwriteIfNewer = "overwrite-if-newer"
+	conflictRename           = "rename"
+	conflictRenameTimestamp  = "rename-timestamp"
+	conflictSkipIfIdentical  = "skip-if-identical"
+)
+
+// result of a conflict resolution
+type conflictAction int
+
+const (
+	// write the file to the (possibly renamed) destination
+	conflictActionWrite conflictAction = iota
+	// replace the existing destination file
+	conflictActionOverwrite
+	// leave the source file untouched
+	conflictActionSkip
+	// the destination already holds an identical copy of the source file
+	conflictActionIdentical
+)
+
+// isConflictStrategy reports whether strategy is a known conflict strategy; empty defaults to skip
+func isConflictStrategy(strategy string) bool {
+	switch strategy {
+	case "", conflictSkip, conflictOverwrite, conflictOverwriteIfNewer, conflictRename, conflictRenameTimestamp, conflictSkipIfIdentical:
+		return true
+	}
+	return false
+}
+
+// plannedDestinations maps destinations claimed by earlier files of a dry run to their source, which is not written yet
+type plannedDestinations map[string]string
+
+// stat returns the file holding the content of path, either the planned source or path itself
+func (p plannedDestinations) stat(path string) (string, os.FileInfo, error) {
+	if src, ok := p[path]; ok {
+		info, err := os.Stat(src)
+		return src, info, err
+	}
+	info, err := os.Stat(path)
+	return path, info, err
+}
+
+// exists reports whether path exists or is claimed by an earlier file
+func (p plannedDestinations) exists(path string) bool {
+	_, _, err := p.stat(path)
+	return !os.IsNotExist(err)
+}
+
+// resolveConflict decides what to do with srcPath if dstPath already exists or is planned and returns the final
+// destination path
+func resolveConflict(strategy string, srcPath string, dstPath string, srcInfo os.FileInfo, planned plannedDestinations) (string, conflictAction, error) {
+	dstContent, dstInfo, err := planned.stat(dstPath)
+	if os.IsNotExist(err) {
+		// no conflict at all
+		return dstPath, conflictActionWrite, nil
+	} else if err != nil {
+		return "", conflictActionSkip, err
+	}
+
+	switch strategy {
+	case conflictOverwrite:
+		return dstPath, conflictActionOverwrite, nil
+
+	case conflictOverwriteIfNewer:
+		if srcInfo.ModTime().After(dstInfo.ModTime()) {
+			return dstPath, conflictActionOverwrite, nil
+		}
+		return dstPath, conflictActionSkip, nil
+
+	case conflictRename:
+		return renameNumbered(dstPath, planned), conflictActionWrite, nil
+
+	case conflictRenameTimestamp:
+		renamed := insertSuffix(dstPath, " "+time.Now().Format("20060102-150405"))
+		if planned.exists(renamed) {
+			renamed = renameNumbered(renamed, planned)
+		}
+		return renamed, conflictActionWrite, nil
+
+	case conflictSkipIfIdentical:
+		identical, err := filesIdentical(srcPath, srcInfo, dstContent, dstInfo)
+		if err != nil {
+			return "", conflictActionSkip, err
+		}
+		if identical {
+			return dstPath, conflictActionIdentical, nil
+		}
+		// keep both files if they differ
+		return renameNumbered(dstPath, planned), conflictActionWrite, nil
+	}
+
+	return dstPath, conflictActionSkip, nil
+}
+
+// renameNumbered appends the first free counter to the file name, e.g. "report (1).pdf"
+func renameNumbered(path string, planned plannedDestinations) string {
+	for i := 1; ; i++ {
+		renamed := insertSuffix(path, fmt.Sprintf(" (%d)", i))
+		if !planned.exists(renamed) {
+			return renamed
+		}
+	}
+}
+
+// insertSuffix adds suffix to the file name in front of the extension
+func insertSuffix(path string, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+// filesIdentical compares size and content of two files, directories are never identical
+func filesIdentical(srcPath string, srcInfo os.FileInfo, dstPath string, dstInfo os.FileInfo) (bool, error) {
+	if srcInfo.IsDir() || dstInfo.IsDir() {
+		return false, nil
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+
+	srcHash, err := fileHash(srcPath, verifySHA256)
+	if err != nil {
+		return false, err
+	}
+	dstHash, err := fileHash(dstPath, verifySHA256)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(srcHash, dstHash), nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helper function to create files with custom content
+func createTestFileContent(t *testing.T, file string, content string) {
+	err := os.WriteFile(file, []byte(content), 0644)
+	if err != nil {
+		t.Errorf("error - creating temporary file at: %q", file)
+	}
+}
+
+func TestResolveConflictNoConflict(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+	srcInfo, _ := os.Stat(src)
+
+	for _, strategy := range []string{"", conflictSkip, conflictOverwrite, conflictRename, conflictSkipIfIdentical} {
+		path, action, err := resolveConflict(strategy, src, dst, srcInfo, nil)
+		if err != nil || path != dst || action != conflictActionWrite {
+			t.Errorf("failed - strategy %q got %q, %v, %v", strategy, path, action, err)
+		}
+	}
+}
+
+func TestResolveConflictStrategies(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+	createTestFileContent(t, dst, "OTHERDATA")
+
+	// make the destination older than the source
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(dst, old, old); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+	srcInfo, _ := os.Stat(src)
+
+	if _, action, _ := resolveConflict("", src, dst, srcInfo, nil); action != conflictActionSkip {
+		t.Errorf("failed - default strategy should skip, got %v", action)
+	}
+	if _, action, _ := resolveConflict(conflictOverwrite, src, dst, srcInfo, nil); action != conflictActionOverwrite {
+		t.Errorf("failed - overwrite strategy should overwrite, got %v", action)
+	}
+	if _, action, _ := resolveConflict(conflictOverwriteIfNewer, src, dst, srcInfo, nil); action != conflictActionOverwrite {
+		t.Errorf("failed - newer source should overwrite, got %v", action)
+	}
+
+	// make the destination newer than the source
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(dst, future, future); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+	if _, action, _ := resolveConflict(conflictOverwriteIfNewer, src, dst, srcInfo, nil); action != conflictActionSkip {
+		t.Errorf("failed - older source should skip, got %v", action)
+	}
+
+	// rename with counter and skip taken names
+	createTestFile(t, filepath.Join(testDir, "dst", "test_1 (1).txt"))
+	path, action, _ := resolveConflict(conflictRename, src, dst, srcInfo, nil)
+	if action != conflictActionWrite || filepath.Base(path) != "test_1 (2).txt" {
+		t.Errorf("failed - got %q but expected %q", filepath.Base(path), "test_1 (2).txt")
+	}
+
+	// rename with timestamp
+	path, action, _ = resolveConflict(conflictRenameTimestamp, src, dst, srcInfo, nil)
+	if action != conflictActionWrite || !strings.HasPrefix(filepath.Base(path), "test_1 ") || filepath.Ext(path) != ".txt" {
+		t.Errorf("failed - got unexpected timestamp name %q", filepath.Base(path))
+	}
+
+	// different content is kept next to each other
+	path, action, _ = resolveConflict(conflictSkipIfIdentical, src, dst, srcInfo, nil)
+	if action != conflictActionWrite || filepath.Base(path) != "test_1 (2).txt" {
+		t.Errorf("failed - differing files should be renamed, got %q, %v", path, action)
+	}
+
+	// identical content is detected
+	createTestFile(t, dst)
+	if _, action, _ := resolveConflict(conflictSkipIfIdentical, src, dst, srcInfo, nil); action != conflictActionIdentical {
+		t.Errorf("failed - identical files should be detected, got %v", action)
+	}
+}
+
+func TestIsConflictStrategy(t *testing.T) {
+	if !isConflictStrategy("") || !isConflictStrategy(conflictRename) {
+		t.Errorf("failed - known strategy rejected")
+	}
+	if isConflictStrategy("replace") {
+		t.Errorf("failed - unknown strategy accepted")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// fileMetadata holds the values available in destination templates
+type fileMetadata struct {
+	Name    string    // file name including the extension, e.g. "report.pdf"
+	Stem    string    // file name without the extension, e.g. "report"
+	Ext     string    // extension without the leading dot, e.g. "pdf"
+	Size    int64     // file size in bytes
+	ModTime time.Time // last modification time
+	Rule    string    // name of the rule the file matched
+}
+
+// newFileMetadata collects the template values of a file matched by a rule
+func newFileMetadata(path string, info os.FileInfo, rule string) fileMetadata {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+
+	return fileMetadata{
+		Name:    name,
+		Stem:    strings.TrimSuffix(name, ext),
+		Ext:     strings.TrimPrefix(ext, "."),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Rule:    rule,
+	}
+}
+
+// parseDestination parses a destination directory which may contain template actions
+func parseDestination(name string, destination string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(destination)
+}
+
+// renderDestination expands the destination template with the metadata of a single file
+func renderDestination(tmpl *template.Template, metadata fileMetadata) (string, error) {
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, metadata); err != nil {
+		return "", err
+	}
+
+	// an unset variable would move files into the working directory
+	destination := buffer.String()
+	if destination == "" {
+		return "", fmt.Errorf("empty destination")
+	}
+	return filepath.Clean(destination), nil
+}
+
+// default permissions of created destination directories
+const defaultDirMode os.FileMode = 0755
+
+// parseDirMode parses an octal permission string like "0750", empty defaults to 0755
+func parseDirMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return defaultDirMode, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid directory mode %q", value)
+	}
+	return os.FileMode(mode), nil
+}
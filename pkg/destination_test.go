@@ -0,0 +1,126 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewFileMetadata(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	file := filepath.Join(testDir, "src", "test_1.txt")
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Errorf("error - reading file: %q", file)
+	}
+
+	metadata := newFileMetadata(file, info, "rule")
+	if metadata.Name != "test_1.txt" || metadata.Stem != "test_1" || metadata.Ext != "txt" {
+		t.Errorf("failed - got %+v for file name parts", metadata)
+	}
+	if metadata.Size != 8 {
+		t.Errorf("failed - got %d but expected %d bytes", metadata.Size, 8)
+	}
+	if metadata.Rule != "rule" {
+		t.Errorf("failed - got %q but expected rule %q", metadata.Rule, "rule")
+	}
+}
+
+func TestRenderDestination(t *testing.T) {
+	metadata := fileMetadata{
+		Name:    "report.pdf",
+		Stem:    "report",
+		Ext:     "pdf",
+		Size:    42,
+		ModTime: time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC),
+		Rule:    "archive",
+	}
+
+	tests := map[string]string{
+		"/archive":                                 "/archive",
+		"/archive/{{.ModTime.Year}}/{{.Ext}}":      "/archive/2026/pdf",
+		"/archive/{{.ModTime.Format \"2006-01\"}}": "/archive/2026-10",
+		"/archive/{{.ModTime.Month}}/{{.Rule}}":    "/archive/October/archive",
+		"/archive/{{.Stem}}-{{.Size}}/":            "/archive/report-42",
+		"archive/{{.Ext}}":                         "archive/pdf",
+	}
+
+	for destination, expected := range tests {
+		tmpl, err := parseDestination("test", destination)
+		if err != nil {
+			t.Errorf("error - parsing destination %q: %v", destination, err)
+			continue
+		}
+		result, err := renderDestination(tmpl, metadata)
+		if err != nil {
+			t.Errorf("error - rendering destination %q: %v", destination, err)
+		}
+		if result != filepath.FromSlash(expected) {
+			t.Errorf("failed - got %q but expected %q", result, expected)
+		}
+	}
+
+	// unknown fields must not silently expand to an empty path segment
+	tmpl, err := parseDestination("test", "/archive/{{.Unknown}}")
+	if err != nil {
+		t.Errorf("error - parsing destination: %v", err)
+	}
+	if _, err := renderDestination(tmpl, metadata); err == nil {
+		t.Errorf("failed - expected error for unknown template field")
+	}
+
+	// empty destinations are rejected
+	for _, destination := range []string{"", "{{if false}}/archive{{end}}"} {
+		tmpl, err := parseDestination("test", destination)
+		if err != nil {
+			t.Errorf("error - parsing destination %q: %v", destination, err)
+			continue
+		}
+		if _, err := renderDestination(tmpl, metadata); err == nil {
+			t.Errorf("failed - expected error for destination %q", destination)
+		}
+	}
+
+	// syntax errors are reported while parsing
+	if _, err := parseDestination("test", "/archive/{{.Ext"); err == nil {
+		t.Errorf("failed - expected error for invalid template")
+	}
+}
+
+func TestParseDirMode(t *testing.T) {
+	tests := map[string]os.FileMode{
+		"":     0755,
+		"0750": 0750,
+		"700":  0700,
+	}
+	for value, expected := range tests {
+		mode, err := parseDirMode(value)
+		if err != nil || mode != expected {
+			t.Errorf("failed - got %v, %v for %q but expected %v", mode, err, value, expected)
+		}
+	}
+
+	for _, value := range []string{"rwx", "0999", "01777"} {
+		if _, err := parseDirMode(value); err == nil {
+			t.Errorf("failed - expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pathSize returns the size of a file or the total size of all regular files within a directory
+func pathSize(path string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// directoryCopy copies the directory tree src to dst, the tree appears at dst only after all files are copied
+func directoryCopy(src string, dst string, options transferOptions) (err error) {
+	if _, err := os.Stat(dst); err == nil && !options.Overwrite {
+		return fmt.Errorf("destination directory already exists: %s", dst)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// copy into a hidden temporary directory next to the destination first
+	tmp, err := createTempDirectory(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(tmp)
+		}
+	}()
+
+	var directories []string
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, rel)
+
+		switch {
+		case info.IsDir():
+			directories = append(directories, path)
+			if rel == "." {
+				return nil
+			}
+			return os.Mkdir(target, 0777)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return fileCopy(path, target, transferOptions{Preserve: options.Preserve, Verify: options.Verify})
+		}
+
+		// sockets, devices and named pipes are not copied
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the temporary directory is private, apply the default permissions unless the source permissions are kept
+	if err = os.Chmod(tmp, defaultDirMode); err != nil {
+		return err
+	}
+
+	// apply the metadata of directories last as copying their content modifies them
+	slices.Reverse(directories)
+	for _, directory := range directories {
+		rel, _ := filepath.Rel(src, directory)
+		info, err := os.Stat(directory)
+		if err != nil {
+			return err
+		}
+		if err := preserveMetadata(directory, filepath.Join(tmp, rel), info, options.Preserve); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dst":   filepath.Join(dst, rel),
+			}).Warn("error preserving metadata")
+		}
+	}
+
+	// replace an existing destination only with the complete copy
+	if _, err = os.Stat(dst); err == nil {
+		if err = os.RemoveAll(dst); err != nil {
+			return err
+		}
+	}
+	err = os.Rename(tmp, filepath.Clean(dst))
+	return err
+}
@@ -0,0 +1,218 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// initDirectoryTestTree creates src/project/node_modules/pkg/index.js with a nested node_modules directory
+func initDirectoryTestTree(t *testing.T, srcDir string) {
+	for _, dir := range []string{"project", "project/node_modules", "project/node_modules/pkg", "project/node_modules/pkg/node_modules"} {
+		createTestDir(t, filepath.Join(srcDir, dir))
+	}
+	createTestFile(t, filepath.Join(srcDir, "project", "main.js"))
+	createTestFile(t, filepath.Join(srcDir, "project", "node_modules", "pkg", "index.js"))
+	if err := os.Symlink("index.js", filepath.Join(srcDir, "project", "node_modules", "pkg", "main.js")); err != nil {
+		t.Errorf("error - creating symlink: %v", err)
+	}
+}
+
+func TestFilesFromDirectoryType(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	initDirectoryTestTree(t, srcDir)
+
+	// matched directories are not descended into
+	filter, _ := ruleFilter{Type: "dir", Patterns: []string{"node_modules"}}.compile()
+	files := filesFromDirectory(srcDir, filter)
+	if len(files) != 1 || files[0] != filepath.Join(srcDir, "project", "node_modules") {
+		t.Errorf("failed - got %q but expected only the outer node_modules directory", files)
+	}
+
+	// files are matched by default and directories never are
+	filter, _ = ruleFilter{Patterns: []string{"*"}}.compile()
+	for _, file := range filesFromDirectory(srcDir, filter) {
+		if info, err := os.Stat(file); err == nil && info.IsDir() {
+			t.Errorf("failed - directory matched by file rule: %q", file)
+		}
+	}
+
+	// directories are matched by the total size of their files
+	filter, _ = ruleFilter{Type: "dir", Patterns: []string{"node_modules"}, MinSize: "1KB"}.compile()
+	if files := filesFromDirectory(srcDir, filter); len(files) != 0 {
+		t.Errorf("failed - got %q but expected no directories", files)
+	}
+
+	if _, err := (ruleFilter{Type: "folder"}).compile(); err == nil {
+		t.Errorf("failed - expected error for invalid type")
+	}
+}
+
+func TestRelocateDirectoryMove(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+	initDirectoryTestTree(t, srcDir)
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"project"})
+	CurrentConfiguration.Relocate[0].Type = "dir"
+	Relocate(false)
+
+	if _, err := os.Stat(filepath.Join(srcDir, "project")); err == nil {
+		t.Errorf("failed - source directory should be moved")
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "project", "node_modules", "pkg", "index.js")); err != nil {
+		t.Errorf("failed - moved directory should keep its content: %v", err)
+	}
+}
+
+func TestRelocateDirectoryCopy(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+	initDirectoryTestTree(t, srcDir)
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"project"})
+	CurrentConfiguration.Relocate[0].Type = "dir"
+	Relocate(false)
+
+	for _, file := range []string{"main.js", "node_modules/pkg/index.js", "node_modules/pkg/node_modules"} {
+		if _, err := os.Stat(filepath.Join(srcDir, "project", file)); err != nil {
+			t.Errorf("failed - source should still exist: %q", file)
+		}
+		if _, err := os.Stat(filepath.Join(dstDir, "project", file)); err != nil {
+			t.Errorf("failed - copy should exist: %q", file)
+		}
+	}
+
+	// symbolic links are copied as links
+	link, err := os.Readlink(filepath.Join(dstDir, "project", "node_modules", "pkg", "main.js"))
+	if err != nil || link != "index.js" {
+		t.Errorf("failed - got link %q but expected %q: %v", link, "index.js", err)
+	}
+
+	// no temporary directory is left behind
+	entries, _ := os.ReadDir(dstDir)
+	if len(entries) != 1 {
+		t.Errorf("failed - unexpected files in destination: %v", entries)
+	}
+}
+
+func TestFileMoveDirectoryAcrossFilesystems(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+	initDirectoryTestTree(t, srcDir)
+
+	// simulate source and destination on different filesystems
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() {
+		rename = os.Rename
+	}()
+
+	src := filepath.Join(srcDir, "project")
+	dst := filepath.Join(dstDir, "project")
+	if err := FileMove(src, dst); err != nil {
+		t.Errorf("error - moving %q to %q: %v", src, dst, err)
+	}
+
+	if _, err := os.Stat(src); err == nil {
+		t.Errorf("failed - source directory should be removed: %q", src)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "node_modules", "pkg", "index.js")); err != nil {
+		t.Errorf("failed - moved directory should keep its content: %v", err)
+	}
+}
+
+func TestCleanupDirectory(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	initDirectoryTestTree(t, srcDir)
+
+	setupCleanupConfig(srcDir, []string{"node_modules"})
+	CurrentConfiguration.Cleanup[0].Type = "dir"
+	Cleanup(false)
+
+	if _, err := os.Stat(filepath.Join(srcDir, "project", "node_modules")); err == nil {
+		t.Errorf("failed - directory should be removed recursively")
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "project", "main.js")); err != nil {
+		t.Errorf("failed - non-matching file should still exist: %v", err)
+	}
+}
+
+func TestFileMoveDirectoryOverwriteFailure(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+	initDirectoryTestTree(t, srcDir)
+
+	src := filepath.Join(srcDir, "project")
+	dst := filepath.Join(dstDir, "project")
+	createTestDir(t, dst)
+	createTestFile(t, filepath.Join(dst, "precious"))
+
+	options := transferOptions{Overwrite: true, Verify: "md5"}
+	for _, err := range []error{syscall.EBUSY, syscall.EXDEV} {
+		// the existing directory is kept if the move fails, also when copying across filesystems fails
+		rename = func(oldpath, newpath string) error {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+		}
+		if fileMove(src, dst, options) == nil {
+			t.Errorf("failed - expected move to fail with %v", err)
+		}
+		rename = os.Rename
+
+		if _, err := os.Stat(filepath.Join(dst, "precious")); err != nil {
+			t.Errorf("failed - existing directory should be kept: %v", err)
+		}
+		if _, err := os.Stat(src); err != nil {
+			t.Errorf("failed - source directory should be kept: %v", err)
+		}
+		if entries, _ := os.ReadDir(dstDir); len(entries) != 1 {
+			t.Errorf("failed - unexpected files in destination: %v", entries)
+		}
+	}
+
+	// a successful move replaces the existing directory
+	if err := fileMove(src, dst, transferOptions{Overwrite: true}); err != nil {
+		t.Errorf("error - moving %q to %q: %v", src, dst, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "precious")); err == nil {
+		t.Errorf("failed - existing directory should be replaced")
+	}
+	if entries, _ := os.ReadDir(dstDir); len(entries) != 1 {
+		t.Errorf("failed - unexpected files in destination: %v", entries)
+	}
+}
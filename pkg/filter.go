@@ -0,0 +1,251 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// types of paths a rule applies to
+const (
+	targetFile = "file"
+	targetDir  = "dir"
+)
+
+// struct representing the file selection shared by all rule types
+type ruleFilter struct {
+	Type      string   `mapstructure:"type"`
+	Patterns  []string `mapstructure:"patterns"`
+	Exclude   []string `mapstructure:"exclude"`
+	OlderThan string   `mapstructure:"olderThan"`
+	NewerThan string   `mapstructure:"newerThan"`
+	MinSize   string   `mapstructure:"minSize"`
+	MaxSize   string   `mapstructure:"maxSize"`
+	MinDepth  int      `mapstructure:"minDepth"`
+	MaxDepth  *int     `mapstructure:"maxDepth"`
+}
+
+// fileFilter is the parsed form of a ruleFilter used while walking a directory
+type fileFilter struct {
+	directories bool
+	patterns    []filePattern
+	exclude     []filePattern
+	olderThan   time.Duration
+	newerThan   time.Duration
+	minSize     int64
+	maxSize     int64
+	minDepth    int
+	maxDepth    int
+	now         time.Time
+}
+
+// newFileFilter returns a filter without any conditions
+func newFileFilter() fileFilter {
+	return fileFilter{
+		maxSize:  math.MaxInt64,
+		maxDepth: -1,
+		now:      time.Now(),
+	}
+}
+
+// compile parses all values of the rule filter
+func (r ruleFilter) compile() (fileFilter, error) {
+	var err error
+	filter := newFileFilter()
+
+	switch r.Type {
+	case "", targetFile:
+	case targetDir:
+		filter.directories = true
+	default:
+		return filter, fmt.Errorf("invalid type: %q", r.Type)
+	}
+
+	if filter.patterns, err = compilePatterns(r.Patterns); err != nil {
+		return filter, err
+	}
+	if filter.exclude, err = compilePatterns(r.Exclude); err != nil {
+		return filter, fmt.Errorf("invalid exclude: %w", err)
+	}
+
+	if r.OlderThan != "" {
+		if filter.olderThan, err = parseAge(r.OlderThan); err != nil {
+			return filter, fmt.Errorf("invalid olderThan: %w", err)
+		}
+	}
+	if r.NewerThan != "" {
+		if filter.newerThan, err = parseAge(r.NewerThan); err != nil {
+			return filter, fmt.Errorf("invalid newerThan: %w", err)
+		}
+	}
+	if r.MinSize != "" {
+		if filter.minSize, err = parseSize(r.MinSize); err != nil {
+			return filter, fmt.Errorf("invalid minSize: %w", err)
+		}
+	}
+	if r.MaxSize != "" {
+		if filter.maxSize, err = parseSize(r.MaxSize); err != nil {
+			return filter, fmt.Errorf("invalid maxSize: %w", err)
+		}
+	}
+
+	if r.MinDepth < 0 {
+		return filter, fmt.Errorf("invalid minDepth: %d", r.MinDepth)
+	}
+	filter.minDepth = r.MinDepth
+
+	if r.MaxDepth != nil {
+		if *r.MaxDepth < 0 || *r.MaxDepth < r.MinDepth {
+			return filter, fmt.Errorf("invalid maxDepth: %d", *r.MaxDepth)
+		}
+		filter.maxDepth = *r.MaxDepth
+	}
+
+	return filter, nil
+}
+
+// matchType reports whether the path is of the type the rule applies to
+func (f fileFilter) matchType(info os.FileInfo) bool {
+	return info.IsDir() == f.directories
+}
+
+// matchDepth checks whether files at the given depth below the source directory may match, where 0 is the top level
+func (f fileFilter) matchDepth(depth int) bool {
+	return depth >= f.minDepth && (f.maxDepth < 0 || depth <= f.maxDepth)
+}
+
+// descend reports whether a directory at the given depth may contain matching files
+func (f fileFilter) descend(depth int) bool {
+	return f.maxDepth < 0 || depth < f.maxDepth
+}
+
+// included reports whether the file matches any of the patterns
+func (f fileFilter) included(name string, rel string) bool {
+	for _, pattern := range f.patterns {
+		if pattern.match(name, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether the file matches any of the exclude patterns
+func (f fileFilter) excluded(name string, rel string) bool {
+	for _, pattern := range f.exclude {
+		if pattern.match(name, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// sized reports whether the filter has any size conditions
+func (f fileFilter) sized() bool {
+	return f.minSize > 0 || f.maxSize < math.MaxInt64
+}
+
+// matchInfo checks the age and size conditions of the filter, size is the total size of a directory's files
+func (f fileFilter) matchInfo(info os.FileInfo, size int64) bool {
+	age := f.now.Sub(info.ModTime())
+
+	if f.olderThan > 0 && age < f.olderThan {
+		return false
+	}
+	if f.newerThan > 0 && age > f.newerThan {
+		return false
+	}
+	if size < f.minSize || size > f.maxSize {
+		return false
+	}
+	return true
+}
+
+var ageUnits = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// parseAge parses a duration like time.ParseDuration but additionally accepts days "d" and weeks "w", e.g. "14d"
+func parseAge(value string) (time.Duration, error) {
+	var err error
+
+	// convert days and weeks to hours
+	hours := ageUnits.ReplaceAllStringFunc(value, func(match string) string {
+		parts := ageUnits.FindStringSubmatch(match)
+		number, parseErr := strconv.ParseFloat(parts[1], 64)
+		if parseErr != nil {
+			err = parseErr
+			return match
+		}
+		if parts[2] == "w" {
+			number *= 7
+		}
+		return strconv.FormatFloat(number*24, 'f', -1, 64) + "h"
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	age, err := time.ParseDuration(hours)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if age < 0 {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return age, nil
+}
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// parseSize parses a size in bytes with an optional decimal or binary unit, e.g. "500KB" or "20GiB"
+func parseSize(value string) (int64, error) {
+	trimmed := strings.TrimSpace(value)
+	index := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if index < 0 {
+		index = len(trimmed)
+	}
+
+	number, err := strconv.ParseFloat(trimmed[:index], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[index:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", value)
+	}
+	return int64(number * unit), nil
+}
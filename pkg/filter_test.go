@@ -0,0 +1,204 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90m":   90 * time.Minute,
+		"36h":   36 * time.Hour,
+		"14d":   14 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"0.5d":  12 * time.Hour,
+	}
+	for value, expected := range tests {
+		age, err := parseAge(value)
+		if err != nil || age != expected {
+			t.Errorf("failed - got %v, %v for %q but expected %v", age, err, value, expected)
+		}
+	}
+
+	for _, value := range []string{"", "14", "14x", "-1d"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("failed - expected error for %q", value)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"10k":    10000,
+		"10KB":   10000,
+		"10KiB":  10240,
+		"1.5MB":  1500000,
+		"2 MiB":  2 << 20,
+		"20GiB":  20 << 30,
+		"1tb":    1000000000000,
+		"1TiB":   1 << 40,
+		" 3 gb ": 3000000000,
+	}
+	for value, expected := range tests {
+		size, err := parseSize(value)
+		if err != nil || size != expected {
+			t.Errorf("failed - got %v, %v for %q but expected %v", size, err, value, expected)
+		}
+	}
+
+	for _, value := range []string{"", "MB", "10XB", "1.2.3"} {
+		if _, err := parseSize(value); err == nil {
+			t.Errorf("failed - expected error for %q", value)
+		}
+	}
+}
+
+func TestRuleFilterCompile(t *testing.T) {
+	filter, err := ruleFilter{OlderThan: "14d", NewerThan: "30d", MinSize: "1KB", MaxSize: "1MB"}.compile()
+	if err != nil {
+		t.Errorf("error - compiling filter: %v", err)
+	}
+	if filter.olderThan != 14*24*time.Hour || filter.newerThan != 30*24*time.Hour {
+		t.Errorf("failed - got unexpected ages %v and %v", filter.olderThan, filter.newerThan)
+	}
+	if filter.minSize != 1000 || filter.maxSize != 1000000 {
+		t.Errorf("failed - got unexpected sizes %v and %v", filter.minSize, filter.maxSize)
+	}
+
+	for _, invalid := range []ruleFilter{{OlderThan: "x"}, {NewerThan: "x"}, {MinSize: "x"}, {MaxSize: "x"}} {
+		if _, err := invalid.compile(); err == nil {
+			t.Errorf("failed - expected error for %+v", invalid)
+		}
+	}
+}
+
+func TestFilesFromDirectoryAgeAndSize(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// make one file old and another one big
+	old := time.Now().Add(-20 * 24 * time.Hour)
+	oldFile := filepath.Join(testDir, "src", "test_1.txt")
+	if err := os.Chtimes(oldFile, old, old); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+	createTestFileContent(t, filepath.Join(testDir, "src", "test_2.txt"), "MORE THAN EIGHT BYTES")
+
+	// only files older than 14 days
+	filter, _ := ruleFilter{Patterns: []string{"*.txt"}, OlderThan: "14d"}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 1 || files[0] != oldFile {
+		t.Errorf("failed - got %q but expected %q", files, oldFile)
+	}
+
+	// only files newer than 14 days
+	filter, _ = ruleFilter{Patterns: []string{"*.txt"}, NewerThan: "14d"}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 4 {
+		t.Errorf("failed - got %q but expected %d files", files, 4)
+	}
+
+	// only files with at least 10 bytes
+	filter, _ = ruleFilter{Patterns: []string{"*.txt"}, MinSize: "10"}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 1 {
+		t.Errorf("failed - got %q but expected %d files", files, 1)
+	}
+
+	// only files with at most 8 bytes
+	filter, _ = ruleFilter{Patterns: []string{"*.txt"}, MaxSize: "8B"}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 4 {
+		t.Errorf("failed - got %q but expected %d files", files, 4)
+	}
+}
+
+func TestFilesFromDirectoryExclude(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// all test files except the first two
+	filter, err := ruleFilter{Patterns: []string{"test_*.txt", "*.txt"}, Exclude: []string{"test_1.txt", "*_2.*"}}.compile()
+	if err != nil {
+		t.Errorf("error - compiling filter: %v", err)
+	}
+	if files := filesFromDirectory(testDir, filter); len(files) != 3 {
+		t.Errorf("failed - got %q but expected %d files", files, 3)
+	}
+
+	// invalid exclude patterns are rejected
+	if _, err := (ruleFilter{Exclude: []string{"[a-"}}).compile(); err == nil {
+		t.Errorf("failed - expected error for invalid exclude pattern")
+	}
+}
+
+func TestFilesFromDirectoryDepth(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	createTestDir(t, filepath.Join(testDir, "src", "nested"))
+	createTestFile(t, filepath.Join(testDir, "src", "nested", "test_6.txt"))
+
+	zero, one := 0, 1
+
+	// nothing at the top level
+	filter, _ := ruleFilter{Patterns: []string{"*.txt"}, MaxDepth: &zero}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 0 {
+		t.Errorf("failed - got %q but expected %d files", files, 0)
+	}
+
+	// only files directly in src
+	filter, _ = ruleFilter{Patterns: []string{"*.txt"}, MaxDepth: &one}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 5 {
+		t.Errorf("failed - got %q but expected %d files", files, 5)
+	}
+
+	// only files in nested directories
+	filter, _ = ruleFilter{Patterns: []string{"*.txt"}, MinDepth: 2}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 1 {
+		t.Errorf("failed - got %q but expected %d files", files, 1)
+	}
+
+	// the source directory itself is never matched
+	filter, _ = ruleFilter{Type: "dir", Patterns: []string{"*"}, MaxDepth: &zero}.compile()
+	if files := filesFromDirectory(testDir, filter); len(files) != 2 {
+		t.Errorf("failed - got %q but expected %d files", files, 2)
+	}
+
+	// invalid depths are rejected
+	negative := -1
+	for _, invalid := range []ruleFilter{{MinDepth: -1}, {MaxDepth: &negative}, {MinDepth: 2, MaxDepth: &one}} {
+		if _, err := invalid.compile(); err == nil {
+			t.Errorf("failed - expected error for %+v", invalid)
+		}
+	}
+}
+
+func TestFileFilterDescend(t *testing.T) {
+	filter := newFileFilter()
+	if !filter.descend(100) {
+		t.Errorf("failed - unlimited filter should always descend")
+	}
+
+	filter.maxDepth = 1
+	if !filter.descend(0) || filter.descend(1) {
+		t.Errorf("failed - filter should only descend into top level directories")
+	}
+}
@@ -0,0 +1,370 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// actions marking a run or a single change of a run as undone in the journal
+const (
+	actionUndo      = "undo"
+	actionUndoEntry = "undo-entry"
+)
+
+// JournalEntry records a single change brot made to the filesystem
+type JournalEntry struct {
+	Run        string    `json:"run"`
+	Time       time.Time `json:"time"`
+	Rule       string    `json:"rule,omitempty"`
+	Action     string    `json:"action"`
+	Src        string    `json:"src,omitempty"`
+	Dst        string    `json:"dst,omitempty"`
+	Quarantine string    `json:"quarantine,omitempty"`
+	// the change replaced an existing destination
+	Overwrite bool `json:"overwrite,omitempty"`
+	// size and modification time of a copy right after it was made
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"modTime,omitzero"`
+	// number of the reversed change within its run, counted from 1, for undo-entry markers
+	Entry int `json:"entry,omitempty"`
+}
+
+// errCopyChanged is returned for copies which were modified after the run
+var errCopyChanged = errors.New("copy changed since the run")
+
+// JournalRun summarizes all changes of a single run
+type JournalRun struct {
+	ID      string
+	Time    time.Time
+	Entries int
+	Undone  bool
+}
+
+// JournalFile returns the path of the journal, by default below $XDG_STATE_HOME
+func JournalFile() string {
+	if CurrentConfiguration.Defaults.Journal != "" {
+		return os.ExpandEnv(CurrentConfiguration.Defaults.Journal)
+	}
+
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, _ := os.UserHomeDir()
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "brot", "journal.jsonl")
+}
+
+// newRunID returns a unique id sorting by the start time of the run
+func newRunID() string {
+	return time.Now().Format("20060102-150405") + "-" + strconv.FormatUint(rand.Uint64()&0xffffff, 36)
+}
+
+// appendJournal adds an entry to the end of the journal
+func appendJournal(entry JournalEntry) error {
+	path := JournalFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readJournal returns all entries of the journal in the order they were written
+func readJournal() ([]JournalEntry, error) {
+	file, err := os.Open(JournalFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// a line may be truncated if brot was interrupted while writing it
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("skip invalid journal entry")
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// JournalRuns lists all runs recorded in the journal, the latest run last
+func JournalRuns() ([]JournalRun, error) {
+	entries, err := readJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []JournalRun
+	index := map[string]int{}
+	for _, entry := range entries {
+		i, ok := index[entry.Run]
+		if !ok {
+			i = len(runs)
+			index[entry.Run] = i
+			runs = append(runs, JournalRun{ID: entry.Run, Time: entry.Time})
+		}
+
+		switch entry.Action {
+		case actionUndo:
+			runs[i].Undone = true
+		case actionUndoEntry:
+		default:
+			runs[i].Entries++
+		}
+	}
+	return runs, nil
+}
+
+// Undo reverses all changes of a run, the latest run which is not undone yet is used if id is empty
+func Undo(id string, dryRun bool) (Result, error) {
+	var result Result
+
+	entries, err := readJournal()
+	if err != nil {
+		return result, err
+	}
+	runs, err := JournalRuns()
+	if err != nil {
+		return result, err
+	}
+
+	// find the run to undo
+	index := slices.IndexFunc(runs, func(run JournalRun) bool { return run.ID == id })
+	if id == "" {
+		index = -1
+		for i := len(runs) - 1; i >= 0; i-- {
+			if !runs[i].Undone {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return result, errors.New("no run to undo")
+		}
+	} else if index < 0 {
+		return result, fmt.Errorf("run not found: %s", id)
+	}
+	run := runs[index]
+	if run.Undone {
+		return result, fmt.Errorf("run already undone: %s", run.ID)
+	}
+
+	log.WithFields(log.Fields{
+		"run": run.ID,
+	}).Info("undo run")
+
+	// number the changes of the run and collect those already reversed by an earlier attempt
+	var changes []JournalEntry
+	reversed := map[int]bool{}
+	for _, entry := range entries {
+		if entry.Run != run.ID {
+			continue
+		}
+		switch entry.Action {
+		case actionUndo:
+		case actionUndoEntry:
+			reversed[entry.Entry] = true
+		default:
+			changes = append(changes, entry)
+		}
+	}
+
+	// reverse the changes in the opposite order they were made
+	rules := map[string]int{}
+	for i := len(changes) - 1; i >= 0; i-- {
+		entry := changes[i]
+
+		ruleIndex, ok := rules[entry.Rule]
+		if !ok {
+			ruleIndex = len(result.Rules)
+			rules[entry.Rule] = ruleIndex
+			result.Rules = append(result.Rules, RuleResult{Name: entry.Rule, dryRun: dryRun})
+		}
+		rule := &result.Rules[ruleIndex]
+		rule.Matched++
+
+		if reversed[i+1] {
+			log.WithFields(log.Fields{
+				"src":    entry.Src,
+				"action": entry.Action,
+			}).Debug("skip change already undone")
+			rule.skip(rule.newRecord(entry.Src, entry.Action))
+			continue
+		}
+
+		// record every reversed change so a retry after a failure does not reverse it again
+		if undoEntry(entry, rule, dryRun) && !dryRun {
+			marker := JournalEntry{Run: run.ID, Time: time.Now(), Rule: entry.Rule, Action: actionUndoEntry, Entry: i + 1}
+			if err := appendJournal(marker); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// never undo a run twice, failed changes may be retried
+	if !dryRun && len(result.Errors()) == 0 {
+		if err := appendJournal(JournalEntry{Run: run.ID, Time: time.Now(), Action: actionUndo}); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// undoEntry reverses a single change and reports whether it was reversed, the record describes the action taken to
+// reverse it
+func undoEntry(entry JournalEntry, result *RuleResult, dryRun bool) bool {
+	var record Record
+	var undo func() error
+
+	switch entry.Action {
+	case relocateMove:
+		record = result.newRecord(entry.Dst, relocateMove)
+		record.Dst = entry.Src
+		undo = func() error {
+			if err := os.MkdirAll(filepath.Dir(entry.Src), defaultDirMode); err != nil {
+				return err
+			}
+			return fileMove(entry.Dst, entry.Src, transferOptions{})
+		}
+	case relocateCopy:
+		record = result.newRecord(entry.Dst, cleanupRemove)
+
+		// never delete changes made to the copy after the run
+		if err := entry.unchangedCopy(); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dst":   entry.Dst,
+			}).Error("skip changed copy")
+			result.fail(record, err)
+			return false
+		}
+		undo = func() error {
+			return FileRemove(entry.Dst)
+		}
+	case cleanupRemove:
+		// a moved file with an identical copy in the destination was removed
+		if entry.Dst == "" {
+			log.WithFields(log.Fields{
+				"src": entry.Src,
+			}).Warn("skip removed file")
+			result.skip(result.newRecord(entry.Src, cleanupRemove))
+			return false
+		}
+		record = result.newRecord(entry.Dst, relocateCopy)
+		record.Dst = entry.Src
+		undo = func() error {
+			return fileCopy(entry.Dst, entry.Src, transferOptions{})
+		}
+	case cleanupTrash:
+		record = result.newRecord(entry.Dst, relocateMove)
+		record.Dst = entry.Src
+		undo = func() error {
+			return restoreTrashedFile(entry.Dst, entry.Src)
+		}
+	case cleanupQuarantine:
+		record = result.newRecord(entry.Quarantine, relocateMove)
+		record.Dst = entry.Src
+		undo = func() error {
+			return QuarantineRestore([]string{entry.Quarantine})
+		}
+	case actionPrune:
+		record = result.newRecord(entry.Src, "mkdir")
+		undo = func() error {
+			return os.MkdirAll(entry.Src, defaultDirMode)
+		}
+	default:
+		result.fail(result.newRecord(entry.Src, entry.Action), fmt.Errorf("invalid action: %q", entry.Action))
+		return false
+	}
+
+	if entry.Overwrite {
+		log.WithFields(log.Fields{
+			"dst": entry.Dst,
+		}).Warn("overwritten file cannot be restored")
+	}
+
+	if !dryRun {
+		if err := undo(); err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"src":    entry.Src,
+				"action": entry.Action,
+			}).Error("error undoing change")
+			result.fail(record, err)
+			return false
+		}
+	}
+	result.succeed(record)
+
+	log.WithFields(log.Fields{
+		"src":    entry.Src,
+		"action": entry.Action,
+	}).Infof("undo %v: %v", entry.Action, entry.Src)
+	return true
+}
+
+// unchangedCopy checks whether the copy still has the size and modification time recorded after copying
+func (entry JournalEntry) unchangedCopy() error {
+	info, err := os.Lstat(entry.Dst)
+	if err != nil {
+		return err
+	}
+	// entries written before the state of copies was recorded cannot be checked
+	if entry.ModTime.IsZero() {
+		return nil
+	}
+	if pathSize(entry.Dst, info) != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		return errCopyChanged
+	}
+	return nil
+}
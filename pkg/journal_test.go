@@ -0,0 +1,273 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// setupJournal points the journal and the quarantine into the test directory
+func setupJournal(t *testing.T, testDir string) {
+	CurrentConfiguration.Defaults.Journal = filepath.Join(testDir, "journal.jsonl")
+	CurrentConfiguration.Defaults.Quarantine = filepath.Join(testDir, "quarantine")
+	t.Cleanup(func() {
+		CurrentConfiguration.Defaults.Journal = ""
+		CurrentConfiguration.Defaults.Quarantine = ""
+	})
+}
+
+func TestJournalRuns(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_*.txt"})
+	Relocate(true)
+	Relocate(false)
+
+	// dry runs are not recorded
+	runs, err := JournalRuns()
+	if err != nil {
+		t.Errorf("error - reading journal: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Entries != 3 || runs[0].Undone {
+		t.Errorf("failed - unexpected runs: %+v", runs)
+	}
+}
+
+func TestUndoMove(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_*.txt"})
+	CurrentConfiguration.Relocate[0].Destination = filepath.Join(dstDir, "{{.Ext}}")
+	CurrentConfiguration.Relocate[0].CreateDestination = true
+	Relocate(false)
+
+	result, err := Undo("", false)
+	if err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+	if result.Rules[0].Moved != 3 || result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	for i := 1; i <= 3; i++ {
+		filename := "file_" + strconv.Itoa(i) + ".txt"
+		if _, err := os.Stat(filepath.Join(srcDir, filename)); err != nil {
+			t.Errorf("failed - file should be moved back: %q", filename)
+		}
+		if _, err := os.Stat(filepath.Join(dstDir, "txt", filename)); err == nil {
+			t.Errorf("failed - file should be gone from destination: %q", filename)
+		}
+	}
+
+	// a run is never undone twice
+	if _, err := Undo("", false); err == nil {
+		t.Errorf("failed - expected error without any run left to undo")
+	}
+}
+
+func TestUndoCopy(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt"})
+	Relocate(false)
+	runs, _ := JournalRuns()
+
+	// a later run is not affected by undoing an earlier one
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_2.txt"})
+	Relocate(false)
+
+	if _, err := Undo(runs[0].ID, false); err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dstDir, "file_1.txt")); err == nil {
+		t.Errorf("failed - copy should be removed")
+	}
+	for _, file := range []string{filepath.Join(srcDir, "file_1.txt"), filepath.Join(dstDir, "file_2.txt")} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("failed - file should still exist: %q", file)
+		}
+	}
+
+	if _, err := Undo(runs[0].ID, false); err == nil {
+		t.Errorf("failed - expected error for a run already undone")
+	}
+	if _, err := Undo("unknown", false); err == nil {
+		t.Errorf("failed - expected error for an unknown run")
+	}
+}
+
+func TestUndoCleanup(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testDir, "data"))
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = cleanupTrash
+	Cleanup(false)
+
+	setupCleanupConfig(srcDir, []string{"file_2.txt"})
+	CurrentConfiguration.Cleanup[0].Mode = cleanupQuarantine
+	Cleanup(false)
+
+	setupCleanupConfig(srcDir, []string{"file_3.txt"})
+	Cleanup(false)
+
+	// removed files cannot be restored
+	result, err := Undo("", false)
+	if err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+	if result.Rules[0].Skipped != 1 || result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	// trashed and quarantined files are restored
+	for i := 0; i < 2; i++ {
+		if _, err := Undo("", false); err != nil {
+			t.Errorf("error - undoing run: %v", err)
+		}
+	}
+	for _, file := range []string{"file_1.txt", "file_2.txt"} {
+		if _, err := os.Stat(filepath.Join(srcDir, file)); err != nil {
+			t.Errorf("failed - file should be restored: %q", file)
+		}
+	}
+	if entries, _ := QuarantineList(); len(entries) != 0 {
+		t.Errorf("failed - quarantine should be empty: %v", entries)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(testDir, "data", "Trash", "info")); len(entries) != 0 {
+		t.Errorf("failed - trash info should be removed: %v", entries)
+	}
+}
+
+func TestUndoDryRun(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	Relocate(false)
+
+	if _, err := Undo("", true); err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "file_1.txt")); err != nil {
+		t.Errorf("failed - file should not be moved back in dry run")
+	}
+	if runs, _ := JournalRuns(); runs[0].Undone {
+		t.Errorf("failed - dry run should not mark the run as undone")
+	}
+}
+
+func TestUndoRetry(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt", "file_2.txt"})
+	Relocate(false)
+
+	// block moving one of the files back
+	blocker := filepath.Join(srcDir, "file_2.txt")
+	createTestFile(t, blocker)
+
+	result, err := Undo("", false)
+	if err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+	if result.Rules[0].Moved != 1 || result.Rules[0].Failed != 1 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	// a retry only reverses the failed change
+	if err := os.Remove(blocker); err != nil {
+		t.Errorf("error - removing file: %v", err)
+	}
+	result, err = Undo("", false)
+	if err != nil {
+		t.Errorf("error - retrying undo: %v", err)
+	}
+	if result.Rules[0].Moved != 1 || result.Rules[0].Skipped != 1 || result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	for i := 1; i <= 2; i++ {
+		filename := "file_" + strconv.Itoa(i) + ".txt"
+		if _, err := os.Stat(filepath.Join(srcDir, filename)); err != nil {
+			t.Errorf("failed - file should be moved back: %q", filename)
+		}
+	}
+	if runs, _ := JournalRuns(); len(runs) != 1 || runs[0].Entries != 2 || !runs[0].Undone {
+		t.Errorf("failed - unexpected runs: %+v", runs)
+	}
+}
+
+func TestUndoChangedCopy(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupJournal(t, testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt", "file_2.txt"})
+	Relocate(false)
+
+	// edit one of the copies after the run
+	createTestFileContent(t, filepath.Join(dstDir, "file_1.txt"), "edited content")
+
+	result, err := Undo("", false)
+	if err != nil {
+		t.Errorf("error - undoing run: %v", err)
+	}
+	if result.Rules[0].Removed != 1 || result.Rules[0].Failed != 1 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dstDir, "file_1.txt")); string(content) != "edited content" {
+		t.Errorf("failed - edited copy should be kept")
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "file_2.txt")); err == nil {
+		t.Errorf("failed - unchanged copy should be removed")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// prefix selecting a regular expression pattern
+const regexpPrefix = "re:"
+
+// filePattern is a compiled pattern of a rule
+//
+// Patterns come in three flavours:
+//   - "re:^IMG_\d{4}\.jpe?g$" a regular expression matched against the file name
+//   - "**/node_modules/**" a glob containing a slash matched against the path relative to the source
+//     directory, where "**" matches any number of directories
+//   - "*.pdf" a glob matched against the file name
+type filePattern struct {
+	glob     string
+	segments []string
+	re       *regexp.Regexp
+}
+
+// compilePattern parses a single pattern and checks its syntax
+func compilePattern(pattern string) (filePattern, error) {
+	if expression, ok := strings.CutPrefix(pattern, regexpPrefix); ok {
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return filePattern{}, err
+		}
+		return filePattern{re: re}, nil
+	}
+
+	if strings.Contains(pattern, "/") {
+		segments := strings.Split(strings.Trim(pattern, "/"), "/")
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return filePattern{}, err
+			}
+		}
+		return filePattern{segments: segments}, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return filePattern{}, err
+	}
+	return filePattern{glob: pattern}, nil
+}
+
+// compilePatterns parses a list of patterns, empty patterns are dropped as they do not match any files
+func compilePatterns(patterns []string) ([]filePattern, error) {
+	var compiled []filePattern
+
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		p, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, p)
+	}
+
+	return compiled, nil
+}
+
+// match checks the pattern against a file's name and its slash separated path relative to the source directory
+func (p filePattern) match(name string, rel string) bool {
+	switch {
+	case p.re != nil:
+		return p.re.MatchString(name)
+	case p.segments != nil:
+		return matchSegments(p.segments, strings.Split(rel, "/"))
+	default:
+		matched, _ := path.Match(p.glob, name)
+		return matched
+	}
+}
+
+// matchSegments matches path segments against pattern segments where "**" matches zero or more segments
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+
+	return len(segments) == 0
+}
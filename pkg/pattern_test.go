@@ -0,0 +1,100 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilePatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		matched bool
+	}{
+		// globs against the file name
+		{"*.pdf", "report.pdf", true},
+		{"*.pdf", "docs/report.pdf", true},
+		{"*.pdf", "report.txt", false},
+		// regular expressions against the file name
+		{`re:^IMG_\d{4}\.(jpe?g|heic)$`, "photos/IMG_0042.jpeg", true},
+		{`re:^IMG_\d{4}\.(jpe?g|heic)$`, "IMG_0042.heic", true},
+		{`re:^IMG_\d{4}\.(jpe?g|heic)$`, "IMG_42.jpg", false},
+		{`re:\.(tmp|bak)$`, "a/b/c.bak", true},
+		// globs against the relative path
+		{"docs/*.pdf", "docs/report.pdf", true},
+		{"docs/*.pdf", "report.pdf", false},
+		{"docs/*.pdf", "other/docs/report.pdf", false},
+		{"/docs/*.pdf", "docs/report.pdf", true},
+		{"**/node_modules/.cache/**", "node_modules/.cache/babel/x.json", true},
+		{"**/node_modules/.cache/**", "a/b/node_modules/.cache/x.json", true},
+		{"**/node_modules/.cache/**", "a/node_modules/x.json", false},
+		{"**/*.log", "build.log", true},
+		{"**/*.log", "a/b/build.log", true},
+		{"a/**/z.txt", "a/z.txt", true},
+		{"a/**/z.txt", "a/b/c/z.txt", true},
+		{"a/**/z.txt", "b/z.txt", false},
+	}
+
+	for _, test := range tests {
+		pattern, err := compilePattern(test.pattern)
+		if err != nil {
+			t.Errorf("error - compiling pattern %q: %v", test.pattern, err)
+			continue
+		}
+		if matched := pattern.match(filepath.Base(test.rel), test.rel); matched != test.matched {
+			t.Errorf("failed - pattern %q on %q got %v but expected %v", test.pattern, test.rel, matched, test.matched)
+		}
+	}
+}
+
+func TestCompilePatterns(t *testing.T) {
+	patterns, err := compilePatterns([]string{"", "*.txt", "re:.*"})
+	if err != nil || len(patterns) != 2 {
+		t.Errorf("failed - got %d patterns and %v but expected %d", len(patterns), err, 2)
+	}
+
+	for _, invalid := range []string{"[a-", "re:(", "docs/[a-/*.txt"} {
+		if _, err := compilePatterns([]string{invalid}); err == nil {
+			t.Errorf("failed - expected error for %q", invalid)
+		}
+	}
+}
+
+func TestFilesFromDirectoryPathPatterns(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	createTestDir(t, filepath.Join(testDir, "src", "nested"))
+	createTestFile(t, filepath.Join(testDir, "src", "nested", "test_6.txt"))
+
+	// match everything in a nested directory only
+	if files := FilesFromDirectory(testDir, []string{"src/nested/*.txt"}); len(files) != 1 {
+		t.Errorf("failed - got %q but expected %d files", files, 1)
+	}
+
+	// match everything at any depth
+	if files := FilesFromDirectory(testDir, []string{"**/test_*.txt"}); len(files) != 5 {
+		t.Errorf("failed - got %q but expected %d files", files, 5)
+	}
+
+	// match by regular expression
+	if files := FilesFromDirectory(testDir, []string{`re:^test_[13]\.txt$`}); len(files) != 2 {
+		t.Errorf("failed - got %q but expected %d files", files, 2)
+	}
+}
@@ -0,0 +1,522 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// version of the plan file format
+const planVersion = 1
+
+// action removing an empty directory
+const actionPrune = "prune"
+
+// Plan lists all actions of relocate and cleanup rules computed in advance
+type Plan struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Entries []PlanEntry `json:"entries"`
+}
+
+// PlanEntry is a single action on a file together with the state of the file when it was planned
+type PlanEntry struct {
+	Rule          string    `json:"rule"`
+	Action        string    `json:"action"`
+	Src           string    `json:"src"`
+	Dst           string    `json:"dst,omitempty"`
+	Overwrite     bool      `json:"overwrite,omitempty"`
+	Identical     bool      `json:"identical,omitempty"`
+	IfNewer       bool      `json:"ifNewer,omitempty"`
+	Preserve      []string  `json:"preserve,omitempty"`
+	Verify        string    `json:"verify,omitempty"`
+	DirectoryMode string    `json:"directoryMode,omitempty"`
+	Root          string    `json:"root,omitempty"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"modTime,omitzero"`
+	Inode         uint64    `json:"inode,omitempty"`
+}
+
+// errSourceChanged is returned for entries whose source was modified after planning
+var errSourceChanged = errors.New("source changed since planning")
+
+// errDestinationChanged is returned for entries whose identical destination was modified or removed after planning
+var errDestinationChanged = errors.New("destination changed since planning")
+
+// newPlanEntry creates an entry recording the current state of the source
+func newPlanEntry(rule string, action string, src string, info os.FileInfo) PlanEntry {
+	entry := PlanEntry{
+		Rule:    rule,
+		Action:  action,
+		Src:     src,
+		Size:    pathSize(src, info),
+		ModTime: info.ModTime(),
+	}
+	if inode, ok := fileInode(info); ok {
+		entry.Inode = inode
+	}
+	return entry
+}
+
+// unchanged checks whether the source still has the size, modification time and inode recorded when planning
+func (entry PlanEntry) unchanged() error {
+	info, err := os.Lstat(entry.Src)
+	if err != nil {
+		return err
+	}
+	if pathSize(entry.Src, info) != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		return errSourceChanged
+	}
+	if inode, ok := fileInode(info); ok && entry.Inode != 0 && inode != entry.Inode {
+		return errSourceChanged
+	}
+	return nil
+}
+
+// identical checks whether the destination still holds an identical copy of the source
+func (entry PlanEntry) identical() error {
+	srcInfo, err := os.Stat(entry.Src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(entry.Dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return errDestinationChanged
+	} else if err != nil {
+		return err
+	}
+
+	identical, err := filesIdentical(entry.Src, srcInfo, entry.Dst, dstInfo)
+	if err != nil {
+		return err
+	}
+	if !identical {
+		return errDestinationChanged
+	}
+	return nil
+}
+
+// newer checks whether the source is still newer than an existing destination
+func (entry PlanEntry) newer() (bool, error) {
+	srcInfo, err := os.Stat(entry.Src)
+	if err != nil {
+		return false, err
+	}
+	dstInfo, err := os.Stat(entry.Dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return srcInfo.ModTime().After(dstInfo.ModTime()), nil
+}
+
+// NewPlan computes the actions of all relocate and cleanup rules without changing anything
+func NewPlan() (Plan, Result) {
+	var result Result
+	plan := Plan{Version: planVersion, Created: time.Now()}
+
+	// log the used configuration file
+	log.Info("use config file: ", viper.ConfigFileUsed())
+
+	// simulate all rules and collect the actions instead, files handled by an earlier rule are skipped by later rules
+	executor := newExecutor(true)
+	executor.plan = &plan
+	executor.scans = newScanCache()
+	for _, item := range CurrentConfiguration.Relocate {
+		rule := relocateItem(item, executor)
+		executor.scans.update(rule)
+		result.Rules = append(result.Rules, rule)
+	}
+	for _, item := range CurrentConfiguration.Cleanup {
+		rule := cleanupItem(item, executor)
+		executor.scans.update(rule)
+		result.Rules = append(result.Rules, rule)
+	}
+
+	return plan, result
+}
+
+// Apply executes exactly the actions of the plan, entries whose source changed since planning fail
+func Apply(plan Plan) Result {
+	var result Result
+
+	executor := newExecutor(false)
+	executor.verify = true
+
+	// group the entries by rule in order of their first appearance
+	rules := map[string]int{}
+	for _, entry := range plan.Entries {
+		index, ok := rules[entry.Rule]
+		if !ok {
+			index = len(result.Rules)
+			rules[entry.Rule] = index
+			result.Rules = append(result.Rules, RuleResult{Name: entry.Rule})
+		}
+
+		rule := &result.Rules[index]
+		if entry.Action != actionPrune {
+			rule.Matched++
+		}
+		executor.execute(entry, rule)
+	}
+
+	return result
+}
+
+// WritePlan writes the plan as indented JSON
+func WritePlan(w io.Writer, plan Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// ReadPlan reads a plan written by WritePlan
+func ReadPlan(r io.Reader) (Plan, error) {
+	var plan Plan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return plan, err
+	}
+	if plan.Version != planVersion {
+		return plan, fmt.Errorf("unsupported plan version: %d", plan.Version)
+	}
+	return plan, nil
+}
+
+// executor performs planned actions and records their outcome
+type executor struct {
+	dryRun bool
+	// run identifies all changes of the executor in the journal
+	run string
+	// verify refuses entries whose source changed since planning
+	verify bool
+	// plan collects all entries instead of only simulating them
+	plan *Plan
+	// destinations claimed by earlier files of a dry run
+	planned plannedDestinations
+	// scans are shared between rules of a run or plan, every rule walks its source directory if nil
+	scans *scanCache
+	// directories already checked for leftovers of interrupted copies
+	cleanedDirectories map[string]bool
+}
+
+func newExecutor(dryRun bool) *executor {
+	return &executor{
+		dryRun:             dryRun,
+		run:                newRunID(),
+		planned:            plannedDestinations{},
+		cleanedDirectories: map[string]bool{},
+	}
+}
+
+// files returns all files of the directory matched by the filter
+func (e *executor) files(directory string, filter fileFilter) []string {
+	if e.scans != nil {
+		return e.scans.files(directory, filter)
+	}
+	return filesFromDirectory(directory, filter)
+}
+
+// execute performs a single planned action
+func (e *executor) execute(entry PlanEntry, result *RuleResult) {
+	if e.plan != nil {
+		e.plan.Entries = append(e.plan.Entries, entry)
+	}
+
+	record := result.newRecord(entry.Src, entry.Action)
+	record.Dst = entry.Dst
+	record.Bytes = entry.Size
+
+	// directories to prune change whenever files within are removed and are only removed if empty anyway
+	if e.verify && entry.Action != actionPrune {
+		if err := entry.unchanged(); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"src":   entry.Src,
+			}).Error("skip changed file")
+			result.fail(record, err)
+			return
+		}
+	}
+
+	switch entry.Action {
+	case relocateMove, relocateCopy:
+		e.transfer(entry, record, result)
+	case cleanupRemove, cleanupTrash, cleanupQuarantine:
+		e.cleanup(entry, record, result)
+	case actionPrune:
+		e.prune(entry, record, result)
+	default:
+		log.WithFields(log.Fields{
+			"action": entry.Action,
+		}).Error("skip invalid action")
+		result.fail(record, fmt.Errorf("invalid action: %q", entry.Action))
+	}
+}
+
+// transfer moves or copies a file
+func (e *executor) transfer(entry PlanEntry, record Record, result *RuleResult) {
+	targetDirectory := filepath.Dir(entry.Dst)
+	srcFile := filepath.Base(entry.Src)
+
+	preserve, err := parsePreserve(entry.Preserve)
+	if err != nil {
+		result.fail(record, err)
+		return
+	}
+
+	// create the destination directory if it is missing
+	if entry.DirectoryMode != "" {
+		if _, err := os.Stat(targetDirectory); os.IsNotExist(err) {
+			dirMode, err := parseDirMode(entry.DirectoryMode)
+			if err != nil {
+				result.fail(record, err)
+				return
+			}
+
+			if !e.dryRun {
+				if err := os.MkdirAll(targetDirectory, dirMode); err != nil {
+					log.WithFields(log.Fields{
+						"error": err,
+						"dst":   targetDirectory,
+					}).Error("error creating directory")
+					result.fail(record, err)
+					return
+				}
+			}
+
+			log.WithFields(log.Fields{
+				"dst": targetDirectory,
+			}).Info("create destination")
+		}
+	}
+
+	// remove temporary files of interrupted copies once per directory
+	if !e.dryRun && !e.cleanedDirectories[targetDirectory] {
+		removeStaleTempFiles(targetDirectory)
+		e.cleanedDirectories[targetDirectory] = true
+	}
+
+	if entry.Identical {
+		// the destination may have changed since it was compared, e.g. while a plan was waiting to be applied
+		if !e.dryRun {
+			if err := entry.identical(); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+					"dst":   entry.Dst,
+				}).Error("error comparing file")
+				result.fail(record, err)
+				return
+			}
+		}
+
+		// an identical copy already exists, a moved file is not needed in the source anymore
+		if entry.Action == relocateMove && !e.dryRun {
+			if err := FileRemove(entry.Src); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+				}).Error("error removing file")
+				result.fail(record, err)
+				return
+			}
+			e.journal(entry, cleanupRemove, entry.Dst, "")
+		}
+		log.WithFields(log.Fields{
+			"src":  entry.Src,
+			"dst":  targetDirectory,
+			"mode": entry.Action,
+		}).Infof("skip identical file: %v", srcFile)
+		result.skip(record)
+		return
+	}
+
+	// the destination may have been updated since the modification times were compared
+	if entry.IfNewer && !e.dryRun {
+		newer, err := entry.newer()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"src":   entry.Src,
+				"dst":   entry.Dst,
+			}).Error("error comparing file")
+			result.fail(record, err)
+			return
+		}
+		if !newer {
+			log.WithFields(log.Fields{
+				"src":  entry.Src,
+				"dst":  targetDirectory,
+				"mode": entry.Action,
+			}).Warnf("skip file: %v", srcFile)
+			result.skip(record)
+			return
+		}
+	}
+
+	options := transferOptions{
+		Overwrite: entry.Overwrite,
+		Preserve:  preserve,
+		Verify:    entry.Verify,
+	}
+
+	if !e.dryRun {
+		switch entry.Action {
+		case relocateMove:
+			if err := fileMove(entry.Src, entry.Dst, options); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+					"dst":   targetDirectory,
+				}).Error("error moving file")
+				result.fail(record, err)
+				return
+			}
+		case relocateCopy:
+			if err := fileCopy(entry.Src, entry.Dst, options); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+					"dst":   targetDirectory,
+				}).Error("error copying file")
+				result.fail(record, err)
+				return
+			}
+		}
+		e.journal(entry, entry.Action, entry.Dst, "")
+	} else {
+		// later files must not be planned to the same destination
+		e.planned[entry.Dst] = entry.Src
+	}
+	result.succeed(record)
+
+	log.WithFields(log.Fields{
+		"src":  entry.Src,
+		"dst":  targetDirectory,
+		"mode": entry.Action,
+	}).Infof("%v file: %v", entry.Action, srcFile)
+}
+
+// cleanup removes, trashes or quarantines a file
+func (e *executor) cleanup(entry PlanEntry, record Record, result *RuleResult) {
+	if !e.dryRun {
+		switch entry.Action {
+		case cleanupRemove:
+			if err := FileRemove(entry.Src); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+				}).Error("error removing file")
+				result.fail(record, err)
+				return
+			}
+			e.journal(entry, cleanupRemove, "", "")
+		case cleanupTrash:
+			trashedPath, err := trashFile(entry.Src)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+				}).Error("error trashing file")
+				result.fail(record, err)
+				return
+			}
+			e.journal(entry, cleanupTrash, trashedPath, "")
+		case cleanupQuarantine:
+			quarantined, err := quarantineFile(entry.Src, entry.Rule)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"src":   entry.Src,
+				}).Error("error quarantining file")
+				result.fail(record, err)
+				return
+			}
+			e.journal(entry, cleanupQuarantine, "", quarantined.ID)
+		}
+
+		// remove directories which only contained the file
+		if entry.Root != "" {
+			removeEmptyParents(entry.Src, entry.Root)
+		}
+	}
+	result.succeed(record)
+
+	log.WithFields(log.Fields{
+		"src":  entry.Src,
+		"mode": entry.Action,
+	}).Infof("%v file: %v", entry.Action, entry.Src)
+}
+
+// prune removes an empty directory
+func (e *executor) prune(entry PlanEntry, record Record, result *RuleResult) {
+	if !e.dryRun {
+		if err := os.Remove(entry.Src); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dir":   entry.Src,
+			}).Error("error removing directory")
+			result.fail(record, err)
+			return
+		}
+		e.journal(entry, actionPrune, "", "")
+	}
+	result.succeed(record)
+
+	log.WithFields(log.Fields{
+		"dir": entry.Src,
+	}).Infof("remove empty directory: %v", entry.Src)
+}
+
+// journal records a change so it can be undone, failures do not invalidate the change itself
+func (e *executor) journal(entry PlanEntry, action string, dst string, quarantine string) {
+	journalEntry := JournalEntry{
+		Run:        e.run,
+		Time:       time.Now(),
+		Rule:       entry.Rule,
+		Action:     action,
+		Src:        entry.Src,
+		Dst:        dst,
+		Quarantine: quarantine,
+		Overwrite:  entry.Overwrite,
+	}
+
+	// remember the state of a copy so undo does not delete changes made to it later
+	if action == relocateCopy {
+		if info, err := os.Lstat(dst); err == nil {
+			journalEntry.Size = pathSize(dst, info)
+			journalEntry.ModTime = info.ModTime()
+		}
+	}
+
+	err := appendJournal(journalEntry)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"src":   entry.Src,
+		}).Warn("error writing journal")
+	}
+}
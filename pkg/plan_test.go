@@ -0,0 +1,255 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPlan(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt", "file_2.txt"})
+	setupCleanupConfig(srcDir, []string{"file_3.txt"})
+	plan, result := NewPlan()
+
+	if len(plan.Entries) != 3 || plan.Version != planVersion {
+		t.Fatalf("failed - got %d entries but expected 3", len(plan.Entries))
+	}
+	entry := plan.Entries[0]
+	if entry.Rule != "test-move" || entry.Action != relocateMove || entry.Dst != filepath.Join(dstDir, "file_1.txt") || entry.Size != 8 {
+		t.Errorf("failed - unexpected entry: %+v", entry)
+	}
+	if plan.Entries[2].Action != cleanupRemove {
+		t.Errorf("failed - got action %q but expected %q", plan.Entries[2].Action, cleanupRemove)
+	}
+	if result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected errors: %v", result.Errors())
+	}
+
+	// planning does not change anything
+	for i := 1; i <= 3; i++ {
+		file := filepath.Join(srcDir, "file_"+strconv.Itoa(i)+".txt")
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("failed - file should still exist: %q", file)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt", "file_2.txt"})
+	setupCleanupConfig(srcDir, []string{"file_3.txt"})
+	plan, _ := NewPlan()
+
+	// files appearing after planning are not touched
+	createTestFile(t, filepath.Join(srcDir, "file_4.txt"))
+	CurrentConfiguration.Relocate[0].Patterns = []string{"*.txt"}
+
+	result := Apply(plan)
+	if len(result.Rules) != 2 || result.Rules[0].Moved != 2 || result.Rules[1].Removed != 1 || result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+
+	for _, file := range []string{filepath.Join(dstDir, "file_1.txt"), filepath.Join(dstDir, "file_2.txt"), filepath.Join(srcDir, "file_4.txt")} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("failed - file should exist: %q", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "file_3.txt")); err == nil {
+		t.Errorf("failed - file should be removed: %q", filepath.Join(srcDir, "file_3.txt"))
+	}
+}
+
+func TestNewPlanOverlappingRules(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// the cleanup rule also matches the file moved by the relocate rule
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	plan, result := NewPlan()
+
+	if len(plan.Entries) != 3 || result.Rules[1].Matched != 2 {
+		t.Fatalf("failed - unexpected plan: %+v", plan.Entries)
+	}
+	for _, entry := range plan.Entries[1:] {
+		if entry.Src == filepath.Join(srcDir, "file_1.txt") {
+			t.Errorf("failed - moved file planned again: %+v", entry)
+		}
+	}
+
+	if result := Apply(plan); result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected errors: %v", result.Errors())
+	}
+}
+
+func TestApplyChangedSource(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt", "file_2.txt"})
+	CurrentConfiguration.Cleanup = nil
+	plan, _ := NewPlan()
+
+	// change the size of one file and the modification time of the other
+	createTestFileContent(t, filepath.Join(srcDir, "file_1.txt"), "changed content")
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(srcDir, "file_2.txt"), modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	result := Apply(plan)
+	if result.Rules[0].Failed != 2 || result.ExitCode() != ExitFailure {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	for _, err := range result.Errors() {
+		if !strings.Contains(err.Error(), errSourceChanged.Error()) {
+			t.Errorf("failed - unexpected error: %v", err)
+		}
+	}
+
+	for _, file := range []string{"file_1.txt", "file_2.txt"} {
+		if _, err := os.Stat(filepath.Join(srcDir, file)); err != nil {
+			t.Errorf("failed - changed file should not be moved: %q", file)
+		}
+	}
+}
+
+func TestApplyChangedDestination(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	createTestFile(t, filepath.Join(dstDir, "file_1.txt"))
+	createTestFileContent(t, filepath.Join(dstDir, "file_2.txt"), "old content")
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dstDir, "file_2.txt"), modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = conflictSkipIfIdentical
+	CurrentConfiguration.Relocate = append(CurrentConfiguration.Relocate, relocateRule{
+		Name:        "test-newer",
+		Source:      srcDir,
+		Destination: dstDir,
+		ruleFilter:  ruleFilter{Patterns: []string{"file_2.txt"}},
+		Mode:        relocateMove,
+		OnConflict:  conflictOverwriteIfNewer,
+	})
+	CurrentConfiguration.Cleanup = nil
+	plan, _ := NewPlan()
+	if len(plan.Entries) != 2 || !plan.Entries[0].Identical || !plan.Entries[1].IfNewer {
+		t.Fatalf("failed - unexpected plan: %+v", plan.Entries)
+	}
+
+	// the identical copy is gone and the other destination was updated after planning
+	if err := os.Remove(filepath.Join(dstDir, "file_1.txt")); err != nil {
+		t.Errorf("error - removing file: %v", err)
+	}
+	createTestFileContent(t, filepath.Join(dstDir, "file_2.txt"), "new content")
+
+	result := Apply(plan)
+	if result.Rules[0].Failed != 1 || result.Rules[1].Skipped != 1 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	for _, file := range []string{"file_1.txt", "file_2.txt"} {
+		if _, err := os.Stat(filepath.Join(srcDir, file)); err != nil {
+			t.Errorf("failed - file should not be moved: %q", file)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(dstDir, "file_2.txt")); string(content) != "new content" {
+		t.Errorf("failed - newer destination should not be overwritten")
+	}
+}
+
+func TestNewPlanConflictRename(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// two files with the same name in different directories
+	createTestDir(t, filepath.Join(srcDir, "a"))
+	createTestDir(t, filepath.Join(srcDir, "b"))
+	createTestFileContent(t, filepath.Join(srcDir, "a", "r.pdf"), "a")
+	createTestFileContent(t, filepath.Join(srcDir, "b", "r.pdf"), "b")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"*.pdf"})
+	CurrentConfiguration.Relocate[0].OnConflict = conflictRename
+	CurrentConfiguration.Cleanup = nil
+	plan, _ := NewPlan()
+
+	if len(plan.Entries) != 2 || plan.Entries[0].Dst == plan.Entries[1].Dst {
+		t.Fatalf("failed - files planned to the same destination: %+v", plan.Entries)
+	}
+	if plan.Entries[1].Dst != filepath.Join(dstDir, "r (1).pdf") {
+		t.Errorf("failed - got %q but expected %q", plan.Entries[1].Dst, filepath.Join(dstDir, "r (1).pdf"))
+	}
+
+	if result := Apply(plan); result.ExitCode() != ExitSuccess {
+		t.Errorf("failed - unexpected errors: %v", result.Errors())
+	}
+}
+
+func TestReadPlan(t *testing.T) {
+	plan := Plan{
+		Version: planVersion,
+		Created: time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+		Entries: []PlanEntry{{Rule: "documents", Action: relocateMove, Src: "/src/a.pdf", Dst: "/dst/a.pdf", Size: 42, Inode: 7}},
+	}
+
+	var buffer bytes.Buffer
+	if err := WritePlan(&buffer, plan); err != nil {
+		t.Errorf("error - writing plan: %v", err)
+	}
+	read, err := ReadPlan(&buffer)
+	if err != nil {
+		t.Errorf("error - reading plan: %v", err)
+	}
+	if !read.Created.Equal(plan.Created) || len(read.Entries) != 1 || read.Entries[0].Dst != "/dst/a.pdf" || read.Entries[0].Inode != 7 {
+		t.Errorf("failed - got %+v but expected %+v", read, plan)
+	}
+
+	if _, err := ReadPlan(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Errorf("failed - expected error for unsupported version")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// file metadata which can be preserved when copying
+const (
+	preserveAll        = "all"
+	preserveMode       = "mode"
+	preserveTimestamps = "timestamps"
+	preserveOwner      = "owner"
+	preserveXattrs     = "xattrs"
+)
+
+// preserveOptions selects the metadata copied from source to destination files
+type preserveOptions struct {
+	Mode       bool
+	Timestamps bool
+	Owner      bool
+	Xattrs     bool
+}
+
+// parsePreserve parses the list of metadata to preserve
+func parsePreserve(values []string) (preserveOptions, error) {
+	var options preserveOptions
+
+	for _, value := range values {
+		switch value {
+		case preserveAll:
+			options = preserveOptions{Mode: true, Timestamps: true, Owner: true, Xattrs: true}
+		case preserveMode:
+			options.Mode = true
+		case preserveTimestamps:
+			options.Timestamps = true
+		case preserveOwner:
+			options.Owner = true
+		case preserveXattrs:
+			options.Xattrs = true
+		default:
+			return options, fmt.Errorf("invalid preserve value %q", value)
+		}
+	}
+
+	return options, nil
+}
+
+// lchown is replaceable to simulate filesystems without ownership in tests
+var lchown = os.Lchown
+
+// preserveMetadata copies the selected metadata of src to dst, a failure of one kind of metadata does not prevent the
+// others from being copied
+func preserveMetadata(src string, dst string, srcInfo os.FileInfo, options preserveOptions) error {
+	var errs []error
+
+	// ownership first as changing it may reset special permission bits
+	if options.Owner {
+		if uid, gid, ok := fileOwner(srcInfo); ok {
+			if err := lchown(dst, uid, gid); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if options.Mode {
+		if err := os.Chmod(dst, srcInfo.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if options.Xattrs {
+		if err := copyXattrs(src, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// timestamps last as all other changes may touch them
+	if options.Timestamps {
+		atime, ok := fileAccessTime(srcInfo)
+		if !ok {
+			atime = srcInfo.ModTime()
+		}
+		if err := os.Chtimes(dst, atime, srcInfo.ModTime()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
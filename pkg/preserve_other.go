@@ -0,0 +1,38 @@
+//go:build !linux && !darwin
+
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"time"
+)
+
+// fileOwner is not supported on this platform
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// fileAccessTime is not supported on this platform
+func fileAccessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// copyXattrs is not supported on this platform
+func copyXattrs(src string, dst string) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePreserve(t *testing.T) {
+	options, err := parsePreserve([]string{"mode", "timestamps"})
+	if err != nil || !options.Mode || !options.Timestamps || options.Owner || options.Xattrs {
+		t.Errorf("failed - got %+v, %v", options, err)
+	}
+
+	options, err = parsePreserve([]string{"all"})
+	if err != nil || !options.Mode || !options.Timestamps || !options.Owner || !options.Xattrs {
+		t.Errorf("failed - got %+v, %v", options, err)
+	}
+
+	if _, err := parsePreserve([]string{"acl"}); err == nil {
+		t.Errorf("failed - expected error for unknown value")
+	}
+}
+
+func TestFileCopyPreserve(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+
+	// give the source file distinctive metadata
+	if err := os.Chmod(src, 0600); err != nil {
+		t.Errorf("error - changing permissions: %v", err)
+	}
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	options := transferOptions{Preserve: preserveOptions{Mode: true, Timestamps: true, Owner: true}}
+	if err := fileCopy(src, dst, options); err != nil {
+		t.Errorf("error - copying %q to %q: %v", src, dst, err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Errorf("failed - destination file missing: %q", dst)
+		return
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("failed - got mode %v but expected %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("failed - got modification time %v but expected %v", info.ModTime(), modTime)
+	}
+}
+
+func TestFileCopyWithoutPreserve(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	if err := FileCopy(src, dst); err != nil {
+		t.Errorf("error - copying %q to %q: %v", src, dst, err)
+	}
+
+	// a plain copy gets the current time
+	info, err := os.Stat(dst)
+	if err != nil || info.ModTime().Equal(modTime) {
+		t.Errorf("failed - modification time should not be preserved by default")
+	}
+}
+
+func TestPreserveMetadataPartialFailure(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "src", "test_2.txt")
+
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		t.Fatalf("error - reading source file: %v", err)
+	}
+
+	// simulate a filesystem refusing to change the owner
+	lchown = func(string, int, int) error { return os.ErrPermission }
+	defer func() { lchown = os.Lchown }()
+
+	options := preserveOptions{Mode: true, Timestamps: true, Owner: true}
+	err = preserveMetadata(src, dst, srcInfo, options)
+	if _, _, ok := fileOwner(srcInfo); ok && !errors.Is(err, os.ErrPermission) {
+		t.Errorf("failed - expected permission error but got: %v", err)
+	}
+
+	// the modification time is kept anyway
+	info, err := os.Stat(dst)
+	if err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("failed - modification time should be preserved despite other errors")
+	}
+}
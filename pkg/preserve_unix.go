@@ -0,0 +1,98 @@
+//go:build linux || darwin
+
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the numeric user and group id of a file
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// copyXattrs copies all extended attributes of src to dst, attributes which cannot be set do not prevent the others
+// from being copied
+func copyXattrs(src string, dst string) error {
+	names, err := listXattrs(src)
+	if xattrsUnsupported(err) {
+		// the source filesystem does not support extended attributes at all
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		size, err := unix.Getxattr(src, name, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		value := make([]byte, size)
+		size, err = unix.Getxattr(src, name, value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := unix.Setxattr(dst, name, value[:size], 0); xattrsUnsupported(err) {
+			// the destination filesystem does not support extended attributes at all
+			return errors.Join(errs...)
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// xattrsUnsupported reports whether err is returned by a filesystem without extended attributes
+func xattrsUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP)
+}
+
+// listXattrs returns the names of all extended attributes of a file
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buffer := make([]byte, size)
+	size, err = unix.Listxattr(path, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buffer[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
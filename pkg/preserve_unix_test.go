@@ -0,0 +1,48 @@
+//go:build linux || darwin
+
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCopyXattrs(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "src", "test_2.txt")
+
+	if err := unix.Setxattr(src, "user.brot", []byte("TESTDATA"), 0); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+
+	if err := copyXattrs(src, dst); err != nil {
+		t.Errorf("error - copying extended attributes: %v", err)
+	}
+
+	value := make([]byte, 64)
+	size, err := unix.Getxattr(dst, "user.brot", value)
+	if err != nil || string(value[:size]) != "TESTDATA" {
+		t.Errorf("failed - extended attribute not copied: %q, %v", value[:size], err)
+	}
+}
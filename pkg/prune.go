@@ -0,0 +1,98 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// removeEmptyParents removes the parent directories of path which became empty, up to but excluding root
+func removeEmptyParents(path string, root string) {
+	root = filepath.Clean(root)
+
+	for directory := filepath.Dir(path); directory != root && isWithinDirectory(directory, root); directory = filepath.Dir(directory) {
+		entries, err := os.ReadDir(directory)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+
+		if err := os.Remove(directory); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dir":   directory,
+			}).Error("error removing directory")
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"dir": directory,
+		}).Infof("remove empty directory: %v", directory)
+	}
+}
+
+// emptyDirectories lists all empty directories below root deepest first, including directories which only contain
+// empty directories, where removed contains paths which are treated as already gone
+func emptyDirectories(root string, removed map[string]bool) []string {
+	var directories []string
+
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("skip reading directory")
+			return nil
+		}
+		if entry.IsDir() && path != root {
+			directories = append(directories, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("error reading directory")
+		return nil
+	}
+
+	// process the deepest directories first so their parents may become empty
+	slices.Reverse(directories)
+
+	var empty []string
+	for _, directory := range directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			continue
+		}
+
+		gone := true
+		for _, entry := range entries {
+			if !removed[filepath.Join(directory, entry.Name())] {
+				gone = false
+				break
+			}
+		}
+		if gone {
+			removed[directory] = true
+			empty = append(empty, directory)
+		}
+	}
+
+	return empty
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanupRemoveEmptyDirs(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	createTestDir(t, filepath.Join(srcDir, "a"))
+	createTestDir(t, filepath.Join(srcDir, "a", "b"))
+	createTestDir(t, filepath.Join(srcDir, "c"))
+	createTestFile(t, filepath.Join(srcDir, "a", "b", "file_4.txt"))
+	createTestFile(t, filepath.Join(srcDir, "c", "file_5.txt"))
+	createTestFile(t, filepath.Join(srcDir, "c", "keep_that.txt"))
+
+	setupCleanupConfig(srcDir, []string{"file_*.txt"})
+	CurrentConfiguration.Cleanup[0].RemoveEmptyDirs = true
+	Cleanup(false)
+
+	// verify directories which became empty are removed
+	if _, err := os.Stat(filepath.Join(srcDir, "a")); err == nil {
+		t.Errorf("failed - empty directory should be removed: %q", filepath.Join(srcDir, "a"))
+	}
+
+	// verify directories with remaining files and the source directory still exist
+	for _, dir := range []string{srcDir, filepath.Join(srcDir, "c")} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("failed - directory should still exist: %q", dir)
+		}
+	}
+}
+
+func TestCleanupRemoveEmptyDirsKeepsSource(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Cleanup[0].RemoveEmptyDirs = true
+	Cleanup(false)
+
+	if _, err := os.Stat(srcDir); err != nil {
+		t.Errorf("failed - source directory should still exist: %q", srcDir)
+	}
+}
+
+func TestCleanupPruneEmptyDirs(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	createTestDir(t, filepath.Join(srcDir, "a"))
+	createTestDir(t, filepath.Join(srcDir, "a", "b"))
+	createTestDir(t, filepath.Join(srcDir, "a", "b", "c"))
+	createTestDir(t, filepath.Join(srcDir, "d"))
+	createTestDir(t, filepath.Join(srcDir, "e"))
+	createTestDir(t, filepath.Join(srcDir, "e", "f"))
+	createTestFile(t, filepath.Join(srcDir, "e", "keep_that.txt"))
+
+	// prune without any patterns
+	setupCleanupConfig(srcDir, nil)
+	CurrentConfiguration.Cleanup[0].PruneEmptyDirs = true
+	Cleanup(false)
+
+	for _, dir := range []string{filepath.Join(srcDir, "a"), filepath.Join(srcDir, "d"), filepath.Join(srcDir, "e", "f")} {
+		if _, err := os.Stat(dir); err == nil {
+			t.Errorf("failed - empty directory should be removed: %q", dir)
+		}
+	}
+
+	for _, file := range []string{srcDir, filepath.Join(srcDir, "e", "keep_that.txt"), filepath.Join(srcDir, "file_1.txt")} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("failed - path should still exist: %q", file)
+		}
+	}
+}
+
+func TestCleanupPruneEmptyDirsDryRun(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	createTestDir(t, filepath.Join(srcDir, "a"))
+	createTestDir(t, filepath.Join(srcDir, "a", "b"))
+
+	setupCleanupConfig(srcDir, nil)
+	CurrentConfiguration.Cleanup[0].PruneEmptyDirs = true
+	Cleanup(true)
+
+	if _, err := os.Stat(filepath.Join(srcDir, "a", "b")); err != nil {
+		t.Errorf("failed - directory should not be removed in dry run: %q", filepath.Join(srcDir, "a", "b"))
+	}
+}
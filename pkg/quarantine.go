@@ -0,0 +1,252 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// name of the file listing all quarantined files
+const quarantineManifest = "manifest.json"
+
+// name of the file locked while the manifest is modified, the manifest itself is replaced on every write
+const quarantineLock = "manifest.lock"
+
+// QuarantineEntry describes a single file in quarantine
+type QuarantineEntry struct {
+	ID   string    `json:"id"`
+	Path string    `json:"path"`
+	Rule string    `json:"rule"`
+	Time time.Time `json:"time"`
+}
+
+// QuarantineDirectory returns the configured quarantine directory or $XDG_DATA_HOME/brot/quarantine
+func QuarantineDirectory() string {
+	if CurrentConfiguration.Defaults.Quarantine != "" {
+		return os.ExpandEnv(CurrentConfiguration.Defaults.Quarantine)
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "brot", "quarantine")
+}
+
+// location of a quarantined file
+func (e QuarantineEntry) quarantinedPath() string {
+	return filepath.Join(QuarantineDirectory(), e.ID, filepath.Base(e.Path))
+}
+
+// quarantineFile moves a file into the quarantine directory and records it in the manifest
+func quarantineFile(src string, rule string) (QuarantineEntry, error) {
+	path, err := filepath.Abs(src)
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return QuarantineEntry{}, fmt.Errorf("file not found: %w", err)
+	}
+
+	unlock, err := lockQuarantine()
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	defer unlock()
+
+	// read the manifest first, a file missing from the manifest could not be found anymore
+	entries, err := QuarantineList()
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+
+	now := time.Now()
+	entry := QuarantineEntry{
+		ID:   now.Format("20060102-150405") + "-" + strconv.FormatUint(rand.Uint64()&0xffffff, 36),
+		Path: path,
+		Rule: rule,
+		Time: now,
+	}
+
+	dst := entry.quarantinedPath()
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return QuarantineEntry{}, err
+	}
+	if err := fileMove(path, dst, transferOptions{}); err != nil {
+		_ = os.Remove(filepath.Dir(dst))
+		return QuarantineEntry{}, err
+	}
+
+	if err := writeQuarantineManifest(append(entries, entry)); err != nil {
+		// move the file back as it cannot be restored without its entry
+		if restoreErr := fileMove(dst, path, transferOptions{}); restoreErr != nil {
+			return QuarantineEntry{}, errors.Join(err, restoreErr)
+		}
+		_ = os.Remove(filepath.Dir(dst))
+		return QuarantineEntry{}, err
+	}
+	return entry, nil
+}
+
+// lockQuarantine prevents other brot processes from modifying the manifest until the returned function is called
+func lockQuarantine() (func(), error) {
+	if err := os.MkdirAll(QuarantineDirectory(), 0700); err != nil {
+		return nil, err
+	}
+	return lockFile(filepath.Join(QuarantineDirectory(), quarantineLock))
+}
+
+// QuarantineList returns all files in quarantine ordered by the time they were quarantined
+func QuarantineList() ([]QuarantineEntry, error) {
+	var entries []QuarantineEntry
+
+	content, err := os.ReadFile(filepath.Join(QuarantineDirectory(), quarantineManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing quarantine manifest: %w", err)
+	}
+	return entries, nil
+}
+
+// QuarantineRestore moves the quarantined files with the given ids back to their original location
+func QuarantineRestore(ids []string) error {
+	unlock, err := lockQuarantine()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := QuarantineList()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		index := slices.IndexFunc(entries, func(e QuarantineEntry) bool { return e.ID == id })
+		if index < 0 {
+			errs = append(errs, fmt.Errorf("quarantine entry not found: %s", id))
+			continue
+		}
+
+		if err := restoreQuarantineEntry(entries[index]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = slices.Delete(entries, index, index+1)
+	}
+
+	if err := writeQuarantineManifest(entries); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// restoreQuarantineEntry moves a single quarantined file back
+func restoreQuarantineEntry(entry QuarantineEntry) error {
+	// the original directory may have been cleaned up in the meantime
+	if err := os.MkdirAll(filepath.Dir(entry.Path), defaultDirMode); err != nil {
+		return err
+	}
+	if err := fileMove(entry.quarantinedPath(), entry.Path, transferOptions{}); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(QuarantineDirectory(), entry.ID))
+}
+
+// QuarantinePurge permanently deletes all quarantined files older than the given duration and returns their number
+func QuarantinePurge(olderThan string) (int, error) {
+	age, err := parseAge(olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	unlock, err := lockQuarantine()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := QuarantineList()
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	var kept []QuarantineEntry
+	purged := 0
+	for _, entry := range entries {
+		if time.Since(entry.Time) < age {
+			kept = append(kept, entry)
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(QuarantineDirectory(), entry.ID)); err != nil {
+			errs = append(errs, err)
+			kept = append(kept, entry)
+			continue
+		}
+		purged++
+	}
+
+	if err := writeQuarantineManifest(kept); err != nil {
+		errs = append(errs, err)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// writeQuarantineManifest replaces the manifest atomically
+func writeQuarantineManifest(entries []QuarantineEntry) error {
+	if entries == nil {
+		entries = []QuarantineEntry{}
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(QuarantineDirectory(), 0700); err != nil {
+		return err
+	}
+	manifest := filepath.Join(QuarantineDirectory(), quarantineManifest)
+	file, err := createTempFile(manifest)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), manifest)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// helper function to use a quarantine directory within the test directory
+func setupQuarantineConfig(t *testing.T, testDir string) string {
+	dir := filepath.Join(testDir, "quarantine")
+	CurrentConfiguration.Defaults.Quarantine = dir
+	t.Cleanup(func() {
+		CurrentConfiguration.Defaults.Quarantine = ""
+	})
+	return dir
+}
+
+func TestQuarantineRestore(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupQuarantineConfig(t, testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+
+	entry, err := quarantineFile(src, "rule")
+	if err != nil {
+		t.Errorf("error - quarantining file: %v", err)
+		return
+	}
+
+	// check if the file was moved into quarantine
+	if _, err := os.Stat(src); err == nil {
+		t.Errorf("failed - source file should not exist: %q", src)
+	}
+	if _, err := os.Stat(entry.quarantinedPath()); err != nil {
+		t.Errorf("failed - quarantined file missing: %q", entry.quarantinedPath())
+	}
+
+	// check the manifest
+	entries, err := QuarantineList()
+	if err != nil || len(entries) != 1 {
+		t.Errorf("failed - got %v, %v but expected a single entry", entries, err)
+		return
+	}
+	if entries[0].ID != entry.ID || entries[0].Path != src || entries[0].Rule != "rule" {
+		t.Errorf("failed - unexpected manifest entry: %+v", entries[0])
+	}
+
+	// restore the file
+	if err := QuarantineRestore([]string{entry.ID}); err != nil {
+		t.Errorf("error - restoring file: %v", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("failed - restored file missing: %q", src)
+	}
+	if entries, _ := QuarantineList(); len(entries) != 0 {
+		t.Errorf("failed - manifest should be empty, got %v", entries)
+	}
+
+	// unknown ids are reported
+	if err := QuarantineRestore([]string{"unknown"}); err == nil {
+		t.Errorf("failed - expected error for unknown id")
+	}
+}
+
+func TestQuarantinePurge(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupQuarantineConfig(t, testDir)
+
+	old, err := quarantineFile(filepath.Join(testDir, "src", "test_1.txt"), "rule")
+	if err != nil {
+		t.Errorf("error - quarantining file: %v", err)
+	}
+	recent, err := quarantineFile(filepath.Join(testDir, "src", "test_2.txt"), "rule")
+	if err != nil {
+		t.Errorf("error - quarantining file: %v", err)
+	}
+
+	// age the first entry
+	entries, _ := QuarantineList()
+	entries[0].Time = time.Now().Add(-48 * time.Hour)
+	if err := writeQuarantineManifest(entries); err != nil {
+		t.Errorf("error - writing manifest: %v", err)
+	}
+
+	purged, err := QuarantinePurge("1d")
+	if err != nil || purged != 1 {
+		t.Errorf("failed - got %d, %v but expected %d purged files", purged, err, 1)
+	}
+	if _, err := os.Stat(old.quarantinedPath()); err == nil {
+		t.Errorf("failed - purged file should not exist: %q", old.quarantinedPath())
+	}
+	if _, err := os.Stat(recent.quarantinedPath()); err != nil {
+		t.Errorf("failed - recent file should still exist: %q", recent.quarantinedPath())
+	}
+
+	if entries, _ := QuarantineList(); len(entries) != 1 || entries[0].ID != recent.ID {
+		t.Errorf("failed - unexpected manifest after purge: %v", entries)
+	}
+
+	if _, err := QuarantinePurge("soon"); err == nil {
+		t.Errorf("failed - expected error for invalid duration")
+	}
+}
+
+func TestQuarantineCorruptManifest(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	dir := setupQuarantineConfig(t, testDir)
+
+	createTestDir(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, quarantineManifest), []byte("{"), 0600); err != nil {
+		t.Fatalf("error - writing manifest: %v", err)
+	}
+
+	// the file stays in place if it cannot be recorded
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	if _, err := quarantineFile(src, "rule"); err == nil {
+		t.Errorf("failed - expected error for corrupt manifest")
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("failed - source file should still exist: %q", src)
+	}
+}
+
+func TestQuarantineConcurrent(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+	setupQuarantineConfig(t, testDir)
+
+	var files []string
+	for i := 0; i < 10; i++ {
+		file := filepath.Join(testDir, "src", "concurrent_"+strconv.Itoa(i)+".txt")
+		createTestFile(t, file)
+		files = append(files, file)
+	}
+
+	// no entry gets lost while several files are quarantined at once
+	var wg sync.WaitGroup
+	for _, file := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := quarantineFile(file, "rule"); err != nil {
+				t.Errorf("error - quarantining file: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if entries, err := QuarantineList(); err != nil || len(entries) != len(files) {
+		t.Errorf("failed - got %d entries but expected %d: %v", len(entries), len(files), err)
+	}
+}
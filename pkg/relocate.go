@@ -16,85 +16,248 @@ limitations under the License.
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"text/template"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func Relocate(dryRun bool) {
+// modes of relocate rules
+const (
+	relocateMove = "move"
+	relocateCopy = "copy"
+)
+
+func Relocate(dryRun bool) Result {
+	var result Result
+
 	// log the used configuration file
 	log.Info("use config file: ", viper.ConfigFileUsed())
 
 	// iterate over relocate definitions from configuration
+	executor := newExecutor(dryRun)
 	for _, item := range CurrentConfiguration.Relocate {
+		result.Rules = append(result.Rules, relocateItem(item, executor))
+	}
+
+	return result
+}
 
-		// expand any environment variables
-		srcDirectory := os.ExpandEnv(item.Source)
-		dstDirectory := os.ExpandEnv(item.Destination)
+// relocateItem moves or copies all files matched by a single relocate rule
+func relocateItem(item relocateRule, executor *executor) (result RuleResult) {
+	result.Name = item.Name
+	result.dryRun = executor.dryRun
 
-		// get files from the source directory
-		relocateFiles := FilesFromDirectory(srcDirectory, item.Patterns)
+	planner, ok := newRelocatePlanner(item, &result)
+	if !ok {
+		return
+	}
+
+	// get files from the source directory
+	relocateFiles := executor.files(planner.srcDirectory, planner.filter)
+	result.Matched = len(relocateFiles)
 
-		// skip the item if there are no files to relocate
-		if relocateFiles == nil {
-			continue
+	// execute each file right after planning it so conflicts with earlier files of the rule are detected
+	for _, srcPath := range relocateFiles {
+		if entry, ok := planner.plan(srcPath, executor.planned, &result); ok {
+			executor.execute(entry, &result)
 		}
+	}
+
+	return
+}
+
+// relocatePlanner holds the parsed values of a relocate rule needed to plan the action for each file
+type relocatePlanner struct {
+	item         relocateRule
+	srcDirectory string
+	filter       fileFilter
+	dirMode      os.FileMode
+	dstTemplate  *template.Template
+}
+
+// newRelocatePlanner parses and checks a relocate rule, invalid rules are recorded in result
+func newRelocatePlanner(item relocateRule, result *RuleResult) (relocatePlanner, bool) {
+	var err error
+	planner := relocatePlanner{item: item}
+
+	// expand any environment variables
+	planner.srcDirectory = os.ExpandEnv(item.Source)
+	dstDirectory := os.ExpandEnv(item.Destination)
+
+	// check for a valid mode
+	if item.Mode != relocateMove && item.Mode != relocateCopy {
+		log.WithFields(log.Fields{
+			"mode": item.Mode,
+		}).Error("skip invalid relocate mode")
+		result.invalid(fmt.Errorf("invalid relocate mode: %q", item.Mode))
+		return planner, false
+	}
+
+	// check for a valid conflict strategy
+	if !isConflictStrategy(item.OnConflict) {
+		log.WithFields(log.Fields{
+			"onConflict": item.OnConflict,
+		}).Error("skip invalid conflict strategy")
+		result.invalid(fmt.Errorf("invalid conflict strategy: %q", item.OnConflict))
+		return planner, false
+	}
+
+	// parse the conditions to select files
+	planner.filter, err = item.compile()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return planner, false
+	}
 
-		// check if the destination directory exists and skip if it is missing
-		if _, err := os.Stat(dstDirectory); os.IsNotExist(err) {
+	// parse the permissions of created directories
+	planner.dirMode, err = parseDirMode(item.DirectoryMode)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return planner, false
+	}
+
+	// parse the metadata to keep when copying
+	if _, err := parsePreserve(item.Preserve); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"rule":  item.Name,
+		}).Error("skip invalid rule")
+		result.invalid(err)
+		return planner, false
+	}
+
+	// check for a valid checksum algorithm
+	if !isVerifyAlgorithm(item.Verify) {
+		log.WithFields(log.Fields{
+			"verify": item.Verify,
+		}).Error("skip invalid checksum algorithm")
+		result.invalid(fmt.Errorf("invalid checksum algorithm: %q", item.Verify))
+		return planner, false
+	}
+
+	// parse the destination which may contain template actions
+	planner.dstTemplate, err = parseDestination(item.Name, dstDirectory)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"dst":   dstDirectory,
+		}).Error("skip invalid destination")
+		result.invalid(err)
+		return planner, false
+	}
+
+	return planner, true
+}
+
+// plan decides where a single file goes, files which are skipped or fail are recorded in result
+func (p relocatePlanner) plan(srcPath string, planned plannedDestinations, result *RuleResult) (PlanEntry, bool) {
+	item := p.item
+	record := result.newRecord(srcPath, item.Mode)
+
+	srcInfo, err := os.Lstat(srcPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// the file was handled by someone else since listing it
+		log.WithFields(log.Fields{
+			"src": srcPath,
+		}).Debug("skip vanished file")
+		result.skip(record)
+		return PlanEntry{}, false
+	} else if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"src":   srcPath,
+		}).Error("error reading file")
+		result.fail(record, err)
+		return PlanEntry{}, false
+	}
+	entry := newPlanEntry(item.Name, item.Mode, srcPath, srcInfo)
+	record.Bytes = entry.Size
+
+	// expand the destination directory for this file
+	targetDirectory, err := renderDestination(p.dstTemplate, newFileMetadata(srcPath, srcInfo, item.Name))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"src":   srcPath,
+		}).Error("error expanding destination")
+		result.fail(record, err)
+		return PlanEntry{}, false
+	}
+	record.Dst = targetDirectory
+
+	// check if the destination directory exists and create it or skip if it is missing
+	if _, err := os.Stat(targetDirectory); os.IsNotExist(err) {
+		if !item.CreateDestination {
 			log.WithFields(log.Fields{
 				"error": err,
 			}).Warn("skip missing destination")
-			continue
+			result.skip(record)
+			return PlanEntry{}, false
 		}
+		entry.DirectoryMode = fmt.Sprintf("%04o", p.dirMode)
+	}
 
-		for _, srcPath := range relocateFiles {
-
-			// assemble full destination path preserving the file's name
-			srcFile := filepath.Base(srcPath)
-			dstPath := filepath.Join(dstDirectory, srcFile)
-
-			// check if a file with the same name exists in destination
-			if _, err := os.Stat(dstPath); err == nil {
-				log.WithFields(log.Fields{
-					"src":  srcPath,
-					"dst":  dstDirectory,
-					"mode": item.Mode,
-				}).Warnf("skip file: %v", srcFile)
-				continue
-			}
-
-			switch item.Mode {
-			case "move":
-				if !dryRun {
-					if err := FileMove(srcPath, dstPath); err != nil {
-						log.WithFields(log.Fields{
-							"error": err,
-							"src":   srcPath,
-							"dst":   dstDirectory,
-						}).Error("error moving file")
-					}
-				}
-			case "copy":
-				if !dryRun {
-					if err := FileCopy(srcPath, dstPath); err != nil {
-						log.WithFields(log.Fields{
-							"error": err,
-							"src":   srcPath,
-							"dst":   dstDirectory,
-						}).Error("error copying file")
-					}
-				}
-			}
-
+	// recreate the directory structure relative to the source directory
+	if item.PreserveTree {
+		rel, err := filepath.Rel(p.srcDirectory, filepath.Dir(srcPath))
+		if err != nil {
 			log.WithFields(log.Fields{
-				"src":  srcPath,
-				"dst":  dstDirectory,
-				"mode": item.Mode,
-			}).Infof("%v file: %v", item.Mode, srcFile)
-
+				"error": err,
+				"src":   srcPath,
+			}).Error("error reading file")
+			result.fail(record, err)
+			return PlanEntry{}, false
 		}
+		targetDirectory = filepath.Join(targetDirectory, rel)
+		entry.DirectoryMode = fmt.Sprintf("%04o", p.dirMode)
 	}
+
+	// assemble full destination path preserving the file's name
+	srcFile := filepath.Base(srcPath)
+	dstPath := filepath.Join(targetDirectory, srcFile)
+
+	// check if a file with the same name exists in destination
+	dstPath, action, err := resolveConflict(item.OnConflict, srcPath, dstPath, srcInfo, planned)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"src":   srcPath,
+			"dst":   targetDirectory,
+		}).Error("error resolving conflict")
+		result.fail(record, err)
+		return PlanEntry{}, false
+	}
+
+	if action == conflictActionSkip {
+		log.WithFields(log.Fields{
+			"src":  srcPath,
+			"dst":  targetDirectory,
+			"mode": item.Mode,
+		}).Warnf("skip file: %v", srcFile)
+		record.Dst = dstPath
+		result.skip(record)
+		return PlanEntry{}, false
+	}
+
+	entry.Dst = dstPath
+	entry.Overwrite = action == conflictActionOverwrite
+	entry.Identical = action == conflictActionIdentical
+	entry.IfNewer = entry.Overwrite && item.OnConflict == conflictOverwriteIfNewer
+	entry.Preserve = item.Preserve
+	entry.Verify = item.Verify
+
+	return entry, true
 }
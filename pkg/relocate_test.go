@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func initRelocateTestDirectory(t *testing.T) string {
@@ -38,13 +39,7 @@ func initRelocateTestDirectory(t *testing.T) string {
 }
 
 func setupRelocateConfig(srcDir, dstDir, mode string, patterns []string) {
-	CurrentConfiguration.Relocate = make([]struct {
-		Name        string   `mapstructure:"name"`
-		Source      string   `mapstructure:"src"`
-		Destination string   `mapstructure:"dst"`
-		Patterns    []string `mapstructure:"patterns"`
-		Mode        string   `mapstructure:"mode"`
-	}, 1)
+	CurrentConfiguration.Relocate = make([]relocateRule, 1)
 	CurrentConfiguration.Relocate[0].Name = "test-" + mode
 	CurrentConfiguration.Relocate[0].Source = srcDir
 	CurrentConfiguration.Relocate[0].Destination = dstDir
@@ -121,6 +116,21 @@ func TestRelocateDryRun(t *testing.T) {
 	}
 }
 
+func TestRelocateInvalidMode(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupRelocateConfig(srcDir, filepath.Join(testDir, "dst"), "link", []string{"*.txt"})
+	if result := Relocate(false); result.ExitCode() != ExitFailure {
+		t.Errorf("failed - expected invalid mode to fail: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "file_1.txt")); err != nil {
+		t.Errorf("failed - file should still exist: %v", err)
+	}
+}
+
 func TestRelocateMissingDestination(t *testing.T) {
 	testDir := initRelocateTestDirectory(t)
 	defer os.RemoveAll(testDir)
@@ -222,13 +232,7 @@ func TestRelocateEnvironmentVariables(t *testing.T) {
 	defer os.Unsetenv(testEnvVar)
 
 	// setup configuration with environment variable in path
-	CurrentConfiguration.Relocate = make([]struct {
-		Name        string   `mapstructure:"name"`
-		Source      string   `mapstructure:"src"`
-		Destination string   `mapstructure:"dst"`
-		Patterns    []string `mapstructure:"patterns"`
-		Mode        string   `mapstructure:"mode"`
-	}, 1)
+	CurrentConfiguration.Relocate = make([]relocateRule, 1)
 	CurrentConfiguration.Relocate[0].Name = "test-env-vars"
 	CurrentConfiguration.Relocate[0].Source = "$" + testEnvVar
 	CurrentConfiguration.Relocate[0].Destination = dstDir
@@ -244,7 +248,255 @@ func TestRelocateEnvironmentVariables(t *testing.T) {
 	}
 }
 
+func TestRelocateTemplateDestination(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// set a well known modification time
+	srcFile := filepath.Join(srcDir, "file_1.txt")
+	modTime := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.Local)
+	if err := os.Chtimes(srcFile, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	// templated subdirectories have to exist
+	createTestDir(t, filepath.Join(dstDir, "2024"))
+	createTestDir(t, filepath.Join(dstDir, "2024", "txt"))
+
+	setupRelocateConfig(srcDir, filepath.Join(dstDir, "{{.ModTime.Year}}", "{{.Ext}}"), "move", []string{"file_*.txt"})
+	Relocate(false)
+
+	// verify file was moved into the expanded directory
+	dstFile := filepath.Join(dstDir, "2024", "txt", "file_1.txt")
+	if _, err := os.Stat(dstFile); err != nil {
+		t.Errorf("failed - destination file missing: %q", dstFile)
+	}
+}
+
+func TestRelocateConflictOverwrite(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	dstFile := filepath.Join(dstDir, "file_1.txt")
+	createTestFileContent(t, dstFile, "OLD")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = "overwrite"
+	Relocate(false)
+
+	// verify destination file was replaced
+	content, err := os.ReadFile(dstFile)
+	if err != nil || string(content) != "TESTDATA" {
+		t.Errorf("failed - destination file was not overwritten: %q", dstFile)
+	}
+}
+
+func TestRelocateConflictRename(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	createTestFileContent(t, filepath.Join(dstDir, "file_1.txt"), "OLD")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = "rename"
+	Relocate(false)
+
+	// verify source was moved next to the existing file
+	dstFile := filepath.Join(dstDir, "file_1 (1).txt")
+	if _, err := os.Stat(dstFile); err != nil {
+		t.Errorf("failed - renamed destination file missing: %q", dstFile)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "file_1.txt")); err == nil {
+		t.Errorf("failed - source file should not exist")
+	}
+}
+
+func TestRelocateConflictSkipIfIdentical(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	createTestFile(t, filepath.Join(dstDir, "file_1.txt"))
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = "skip-if-identical"
+	Relocate(false)
+
+	// verify the redundant source file was removed without creating a copy
+	if _, err := os.Stat(filepath.Join(srcDir, "file_1.txt")); err == nil {
+		t.Errorf("failed - identical source file should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "file_1 (1).txt")); err == nil {
+		t.Errorf("failed - identical file should not be renamed")
+	}
+}
+
+func TestRelocateInvalidConflictStrategy(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
 
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = "replace"
+	Relocate(false)
 
+	// verify the rule was skipped entirely
+	if _, err := os.Stat(filepath.Join(srcDir, "file_1.txt")); err != nil {
+		t.Errorf("failed - source file should still exist for invalid rule")
+	}
+}
 
+func TestRelocateMaxDepth(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
 
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// create a file buried in a project folder
+	createTestDir(t, filepath.Join(srcDir, "project"))
+	nestedFile := filepath.Join(srcDir, "project", "file_4.txt")
+	createTestFile(t, nestedFile)
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_*.txt"})
+	maxDepth := 0
+	CurrentConfiguration.Relocate[0].MaxDepth = &maxDepth
+	Relocate(false)
+
+	// verify top level files were moved but the nested one was left alone
+	if _, err := os.Stat(filepath.Join(dstDir, "file_1.txt")); err != nil {
+		t.Errorf("failed - top level file should be moved")
+	}
+	if _, err := os.Stat(nestedFile); err != nil {
+		t.Errorf("failed - nested file should still exist: %q", nestedFile)
+	}
+}
+
+func TestRelocatePreserveTree(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// create files sharing a name in different subdirectories
+	createTestDir(t, filepath.Join(srcDir, "a"))
+	createTestDir(t, filepath.Join(srcDir, "b"))
+	createTestDir(t, filepath.Join(srcDir, "b", "c"))
+	createTestFile(t, filepath.Join(srcDir, "a", "same.txt"))
+	createTestFile(t, filepath.Join(srcDir, "b", "c", "same.txt"))
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"*.txt"})
+	CurrentConfiguration.Relocate[0].PreserveTree = true
+	Relocate(false)
+
+	// verify the relative structure was recreated
+	for _, file := range []string{"file_1.txt", filepath.Join("a", "same.txt"), filepath.Join("b", "c", "same.txt")} {
+		if _, err := os.Stat(filepath.Join(dstDir, file)); err != nil {
+			t.Errorf("failed - destination file missing: %q", file)
+		}
+		if _, err := os.Stat(filepath.Join(srcDir, file)); err == nil {
+			t.Errorf("failed - source file should not exist: %q", file)
+		}
+	}
+}
+
+func TestRelocateCreateDestination(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, filepath.Join(dstDir, "new", "{{.Ext}}"), "copy", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].CreateDestination = true
+	CurrentConfiguration.Relocate[0].DirectoryMode = "0700"
+	Relocate(false)
+
+	// verify the missing destination was created with the configured permissions
+	dstFile := filepath.Join(dstDir, "new", "txt", "file_1.txt")
+	if _, err := os.Stat(dstFile); err != nil {
+		t.Errorf("failed - destination file missing: %q", dstFile)
+	}
+	info, err := os.Stat(filepath.Join(dstDir, "new", "txt"))
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("failed - destination directory not created with expected permissions")
+	}
+}
+
+func TestRelocateCreateDestinationDryRun(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst", "new")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].CreateDestination = true
+	Relocate(true)
+
+	// verify nothing was created
+	if _, err := os.Stat(dstDir); err == nil {
+		t.Errorf("failed - destination should not be created in dryRun mode: %q", dstDir)
+	}
+}
+
+func TestRelocateVerify(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt"})
+	CurrentConfiguration.Relocate[0].Verify = "crc64"
+	Relocate(false)
+
+	dstFile := filepath.Join(dstDir, "file_1.txt")
+	if _, err := os.Stat(dstFile); err != nil {
+		t.Errorf("failed - destination file missing: %q", dstFile)
+	}
+
+	// an invalid algorithm skips the rule
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_2.txt"})
+	CurrentConfiguration.Relocate[0].Verify = "md5"
+	Relocate(false)
+
+	srcFile := filepath.Join(srcDir, "file_2.txt")
+	if _, err := os.Stat(srcFile); err != nil {
+		t.Errorf("failed - source file should still exist for invalid rule: %q", srcFile)
+	}
+}
+
+func TestRelocateEmptyDestination(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	os.Unsetenv("BROT_TEST_UNSET_DIR")
+
+	// an unset variable must not move files into the working directory
+	setupRelocateConfig(srcDir, "$BROT_TEST_UNSET_DIR", "move", []string{"file_1.txt"})
+	result := Relocate(false)
+
+	if result.Rules[0].Failed != 1 || result.ExitCode() == ExitSuccess {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	srcFile := filepath.Join(srcDir, "file_1.txt")
+	if _, err := os.Stat(srcFile); err != nil {
+		t.Errorf("failed - source file should still exist: %q", srcFile)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// outcomes of a single file action
+const (
+	OutcomeSuccess = "success"
+	OutcomeDryRun  = "dry-run"
+	OutcomeSkipped = "skipped"
+	OutcomeFailed  = "failed"
+)
+
+// formats of run reports
+const (
+	ReportJSON  = "json"
+	ReportYAML  = "yaml"
+	ReportCSV   = "csv"
+	ReportTable = "table"
+)
+
+// Record describes the action taken on a single file, the duration is written in nanoseconds to JSON and CSV and as
+// duration string like "1.5ms" to YAML and tables
+type Record struct {
+	Rule     string        `json:"rule" yaml:"rule"`
+	Src      string        `json:"src" yaml:"src"`
+	Dst      string        `json:"dst" yaml:"dst"`
+	Action   string        `json:"action" yaml:"action"`
+	Outcome  string        `json:"outcome" yaml:"outcome"`
+	Bytes    int64         `json:"bytes" yaml:"bytes"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Error    string        `json:"error" yaml:"error"`
+
+	start time.Time
+}
+
+// IsReportFormat checks whether format is a supported report format
+func IsReportFormat(format string) bool {
+	switch format {
+	case ReportJSON, ReportYAML, ReportCSV, ReportTable:
+		return true
+	}
+	return false
+}
+
+// WriteReport writes all records in the given format
+func WriteReport(w io.Writer, format string, records []Record) error {
+	// always write a list, even without any records
+	if records == nil {
+		records = []Record{}
+	}
+
+	switch format {
+	case ReportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+
+	case ReportYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return encoder.Close()
+
+	case ReportCSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"rule", "src", "dst", "action", "outcome", "bytes", "duration", "error"})
+		for _, record := range records {
+			_ = writer.Write([]string{
+				record.Rule,
+				record.Src,
+				record.Dst,
+				record.Action,
+				record.Outcome,
+				strconv.FormatInt(record.Bytes, 10),
+				strconv.FormatInt(int64(record.Duration), 10),
+				record.Error,
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case ReportTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "RULE\tACTION\tOUTCOME\tBYTES\tDURATION\tSRC\tDST\tERROR")
+		for _, record := range records {
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", record.Rule, record.Action, record.Outcome,
+				record.Bytes, record.Duration, record.Src, record.Dst, record.Error)
+		}
+		return writer.Flush()
+	}
+
+	return fmt.Errorf("invalid report format: %q", format)
+}
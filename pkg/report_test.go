@@ -0,0 +1,144 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+var testRecords = []Record{
+	{Rule: "documents", Src: "/src/a.pdf", Dst: "/dst/a.pdf", Action: "move", Outcome: OutcomeSuccess, Bytes: 42, Duration: time.Millisecond},
+	{Rule: "documents", Src: "/src/b,c.pdf", Action: "move", Outcome: OutcomeFailed, Error: "permission denied"},
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteReport(&buffer, ReportJSON, testRecords); err != nil {
+		t.Errorf("error - writing report: %v", err)
+	}
+
+	var records []Record
+	if err := json.Unmarshal(buffer.Bytes(), &records); err != nil {
+		t.Errorf("error - parsing report: %v", err)
+	}
+	if len(records) != 2 || records[0].Bytes != 42 || records[0].Duration != time.Millisecond || records[1].Error != "permission denied" {
+		t.Errorf("failed - unexpected records: %+v", records)
+	}
+
+	// an empty report is an empty list
+	buffer.Reset()
+	_ = WriteReport(&buffer, ReportJSON, nil)
+	if strings.TrimSpace(buffer.String()) != "[]" {
+		t.Errorf("failed - got %q but expected an empty list", buffer.String())
+	}
+}
+
+func TestWriteReportYAML(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteReport(&buffer, ReportYAML, testRecords); err != nil {
+		t.Errorf("error - writing report: %v", err)
+	}
+
+	var records []Record
+	if err := yaml.Unmarshal(buffer.Bytes(), &records); err != nil {
+		t.Errorf("error - parsing report: %v", err)
+	}
+	if len(records) != 2 || records[0].Dst != "/dst/a.pdf" || records[0].Duration != time.Millisecond {
+		t.Errorf("failed - unexpected records: %+v", records)
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteReport(&buffer, ReportCSV, testRecords); err != nil {
+		t.Errorf("error - writing report: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Errorf("error - parsing report: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "rule" || rows[1][6] != "1000000" || rows[2][1] != "/src/b,c.pdf" {
+		t.Errorf("failed - unexpected rows: %q", rows)
+	}
+}
+
+func TestWriteReportInvalidFormat(t *testing.T) {
+	if IsReportFormat("xml") {
+		t.Errorf("failed - xml should not be a report format")
+	}
+	if err := WriteReport(&bytes.Buffer{}, "xml", testRecords); err == nil {
+		t.Errorf("failed - expected error for invalid format")
+	}
+}
+
+func TestRelocateRecords(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "copy", []string{"file_1.txt"})
+	result := Relocate(true)
+
+	records := result.Records()
+	if len(records) != 1 {
+		t.Fatalf("failed - got %d records but expected 1", len(records))
+	}
+	expected := Record{
+		Rule:    "test-copy",
+		Src:     filepath.Join(srcDir, "file_1.txt"),
+		Dst:     filepath.Join(dstDir, "file_1.txt"),
+		Action:  "copy",
+		Outcome: OutcomeDryRun,
+		Bytes:   8,
+	}
+	record := records[0]
+	record.Duration, record.start = 0, time.Time{}
+	if record != expected {
+		t.Errorf("failed - got %+v but expected %+v", record, expected)
+	}
+}
+
+func TestCleanupRecords(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	setupCleanupConfig(filepath.Join(testDir, "src"), []string{"file_*.txt"})
+	CurrentConfiguration.Cleanup[0].Keep = 1
+	result := Cleanup(false)
+
+	outcomes := map[string]int{}
+	for _, record := range result.Records() {
+		if record.Action != cleanupRemove {
+			t.Errorf("failed - got action %q but expected %q", record.Action, cleanupRemove)
+		}
+		outcomes[record.Outcome]++
+	}
+	if outcomes[OutcomeSuccess] != 2 || outcomes[OutcomeSkipped] != 1 {
+		t.Errorf("failed - unexpected outcomes: %v", outcomes)
+	}
+}
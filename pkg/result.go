@@ -0,0 +1,143 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"time"
+)
+
+// exit codes of commands applying rules
+const (
+	ExitSuccess        = 0
+	ExitPartialFailure = 2
+	ExitFailure        = 3
+)
+
+// RuleResult counts the outcome of all files handled by a single rule
+type RuleResult struct {
+	Name    string
+	Matched int
+	Moved   int
+	Copied  int
+	Removed int
+	Skipped int
+	Failed  int
+	Errors  []error
+	Records []Record
+
+	dryRun bool
+}
+
+// Result collects the outcome of all rules applied in one run
+type Result struct {
+	Rules []RuleResult
+}
+
+// newRecord starts recording the action taken on a file
+func (r *RuleResult) newRecord(src string, action string) Record {
+	return Record{Rule: r.Name, Src: src, Action: action, start: time.Now()}
+}
+
+// succeed records a file which was moved, copied or removed
+func (r *RuleResult) succeed(record Record) {
+	switch record.Action {
+	case relocateMove:
+		r.Moved++
+	case relocateCopy:
+		r.Copied++
+	case cleanupRemove, cleanupTrash, cleanupQuarantine:
+		r.Removed++
+	}
+
+	record.Outcome = OutcomeSuccess
+	if r.dryRun {
+		record.Outcome = OutcomeDryRun
+	}
+	r.finish(record)
+}
+
+// skip records a file which was matched but left alone
+func (r *RuleResult) skip(record Record) {
+	r.Skipped++
+	record.Outcome = OutcomeSkipped
+	r.finish(record)
+}
+
+// fail records a file which could not be handled
+func (r *RuleResult) fail(record Record, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, fmt.Errorf("%s: %w", record.Src, err))
+	record.Outcome = OutcomeFailed
+	record.Error = err.Error()
+	r.finish(record)
+}
+
+// finish adds the record with the time it took
+func (r *RuleResult) finish(record Record) {
+	record.Duration = time.Since(record.start)
+	r.Records = append(r.Records, record)
+}
+
+// invalid records a rule which could not be applied at all
+func (r *RuleResult) invalid(err error) {
+	r.Errors = append(r.Errors, fmt.Errorf("rule %q: %w", r.Name, err))
+}
+
+// Succeeded returns the number of files moved, copied or removed by the rule
+func (r RuleResult) Succeeded() int {
+	return r.Moved + r.Copied + r.Removed
+}
+
+// Succeeded returns the number of files moved, copied or removed by all rules
+func (r Result) Succeeded() int {
+	succeeded := 0
+	for _, rule := range r.Rules {
+		succeeded += rule.Succeeded()
+	}
+	return succeeded
+}
+
+// Errors returns the errors of all rules
+func (r Result) Errors() []error {
+	var errs []error
+	for _, rule := range r.Rules {
+		errs = append(errs, rule.Errors...)
+	}
+	return errs
+}
+
+// Records returns the records of all rules
+func (r Result) Records() []Record {
+	var records []Record
+	for _, rule := range r.Rules {
+		records = append(records, rule.Records...)
+	}
+	return records
+}
+
+// ExitCode maps the result to the exit code of the command, partial failures are distinguished from runs where
+// nothing succeeded
+func (r Result) ExitCode() int {
+	switch {
+	case len(r.Errors()) == 0:
+		return ExitSuccess
+	case r.Succeeded() > 0:
+		return ExitPartialFailure
+	default:
+		return ExitFailure
+	}
+}
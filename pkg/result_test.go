@@ -0,0 +1,101 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResultExitCode(t *testing.T) {
+	tests := []struct {
+		result   Result
+		expected int
+	}{
+		{Result{}, ExitSuccess},
+		{Result{Rules: []RuleResult{{Moved: 2}}}, ExitSuccess},
+		{Result{Rules: []RuleResult{{Moved: 2}, {Failed: 1, Errors: []error{errors.New("failed")}}}}, ExitPartialFailure},
+		{Result{Rules: []RuleResult{{Failed: 1, Errors: []error{errors.New("failed")}}}}, ExitFailure},
+		{Result{Rules: []RuleResult{{Skipped: 1}, {Errors: []error{errors.New("invalid")}}}}, ExitFailure},
+	}
+
+	for _, test := range tests {
+		if code := test.result.ExitCode(); code != test.expected {
+			t.Errorf("failed - got exit code %d but expected %d for %+v", code, test.expected, test.result)
+		}
+	}
+}
+
+func TestRelocateResult(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	// a directory in the way of file_1.txt cannot be overwritten by a file
+	createTestDir(t, filepath.Join(dstDir, "file_1.txt"))
+	createTestFile(t, filepath.Join(dstDir, "file_1.txt", "blocker"))
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_*.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = conflictOverwrite
+	result := Relocate(false)
+
+	if len(result.Rules) != 1 {
+		t.Fatalf("failed - got %d rule results but expected 1", len(result.Rules))
+	}
+	rule := result.Rules[0]
+	if rule.Name != "test-move" || rule.Matched != 3 || rule.Moved != 2 || rule.Failed != 1 || len(rule.Errors) != 1 {
+		t.Errorf("failed - unexpected result: %+v", rule)
+	}
+	if code := result.ExitCode(); code != ExitPartialFailure {
+		t.Errorf("failed - got exit code %d but expected %d", code, ExitPartialFailure)
+	}
+}
+
+func TestRelocateResultInvalidRule(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	setupRelocateConfig(filepath.Join(testDir, "src"), filepath.Join(testDir, "dst"), "copy", []string{"*.txt"})
+	CurrentConfiguration.Relocate[0].OnConflict = "invalid"
+	result := Relocate(false)
+
+	if len(result.Errors()) != 1 || result.ExitCode() != ExitFailure {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+}
+
+func TestCleanupResult(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"file_*.txt"})
+	CurrentConfiguration.Cleanup[0].Keep = 1
+	result := Cleanup(true)
+
+	rule := result.Rules[0]
+	if rule.Matched != 3 || rule.Removed != 2 || rule.Skipped != 1 || rule.Failed != 0 {
+		t.Errorf("failed - unexpected result: %+v", rule)
+	}
+	if code := result.ExitCode(); code != ExitSuccess {
+		t.Errorf("failed - got exit code %d but expected %d", code, ExitSuccess)
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// default layout of dates parsed from file names
+const defaultDateLayout = "2006-01-02"
+
+// struct representing the grandfather-father-son retention of a cleanup rule
+type retentionBuckets struct {
+	Daily       int    `mapstructure:"daily"`
+	Weekly      int    `mapstructure:"weekly"`
+	Monthly     int    `mapstructure:"monthly"`
+	Yearly      int    `mapstructure:"yearly"`
+	DatePattern string `mapstructure:"datePattern"`
+	DateLayout  string `mapstructure:"dateLayout"`
+}
+
+// retentionPolicy decides which of the matched files of a cleanup rule are kept
+type retentionPolicy struct {
+	keep        int
+	keepDays    int
+	daily       int
+	weekly      int
+	monthly     int
+	yearly      int
+	datePattern *regexp.Regexp
+	dateLayout  string
+	now         time.Time
+}
+
+// retentionFile is a matched file with the date used for retention
+type retentionFile struct {
+	path string
+	date time.Time
+}
+
+// retention parses the retention options of a cleanup rule
+func (item cleanupRule) retention() (retentionPolicy, error) {
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"keep", item.Keep},
+		{"keepDays", item.KeepDays},
+		{"retention.daily", item.Retention.Daily},
+		{"retention.weekly", item.Retention.Weekly},
+		{"retention.monthly", item.Retention.Monthly},
+		{"retention.yearly", item.Retention.Yearly},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			return retentionPolicy{}, fmt.Errorf("invalid %s: %d", count.name, count.value)
+		}
+	}
+
+	policy := retentionPolicy{
+		keep:       item.Keep,
+		keepDays:   item.KeepDays,
+		daily:      item.Retention.Daily,
+		weekly:     item.Retention.Weekly,
+		monthly:    item.Retention.Monthly,
+		yearly:     item.Retention.Yearly,
+		dateLayout: item.Retention.DateLayout,
+		now:        time.Now(),
+	}
+
+	if item.Retention.DatePattern != "" {
+		pattern, err := regexp.Compile(item.Retention.DatePattern)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retention.datePattern: %w", err)
+		}
+		policy.datePattern = pattern
+	}
+	if policy.dateLayout == "" {
+		policy.dateLayout = defaultDateLayout
+	}
+
+	return policy, nil
+}
+
+// enabled reports whether the policy keeps any files at all
+func (p retentionPolicy) enabled() bool {
+	return p.keep > 0 || p.keepDays > 0 || p.daily > 0 || p.weekly > 0 || p.monthly > 0 || p.yearly > 0
+}
+
+// apply returns the files which are not retained by the policy
+//
+// Files are grouped by their directory and in each directory the newest files are kept.
+func (p retentionPolicy) apply(files []string) []string {
+	if !p.enabled() {
+		return files
+	}
+
+	groups := map[string][]retentionFile{}
+	retained := map[string]bool{}
+	for _, path := range files {
+		date, err := p.fileDate(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"file":  path,
+			}).Warn("keep file without date")
+			retained[path] = true
+			continue
+		}
+		directory := filepath.Dir(path)
+		groups[directory] = append(groups[directory], retentionFile{path: path, date: date})
+	}
+
+	for _, group := range groups {
+		// newest files first
+		slices.SortStableFunc(group, func(a, b retentionFile) int {
+			return b.date.Compare(a.date)
+		})
+
+		for i, file := range group {
+			if p.retained(i, file) {
+				retained[file.path] = true
+			}
+		}
+
+		for _, file := range p.bucketed(group) {
+			retained[file.path] = true
+		}
+	}
+
+	// keep the walk order of the remaining files
+	var obsolete []string
+	for _, path := range files {
+		if retained[path] {
+			log.WithFields(log.Fields{
+				"file": path,
+			}).Debug("keep file")
+			continue
+		}
+		obsolete = append(obsolete, path)
+	}
+	return obsolete
+}
+
+// fileDate returns the date of a file either parsed from its name or its modification time
+func (p retentionPolicy) fileDate(path string) (time.Time, error) {
+	if p.datePattern == nil {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return info.ModTime(), nil
+	}
+
+	// use the first group of the pattern or the whole match
+	match := p.datePattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("date pattern does not match")
+	}
+	value := match[0]
+	if len(match) > 1 {
+		value = match[1]
+	}
+	return time.ParseInLocation(p.dateLayout, value, time.Local)
+}
+
+// retained reports whether the file at the given position of its newest first group is kept
+func (p retentionPolicy) retained(index int, file retentionFile) bool {
+	if index < p.keep {
+		return true
+	}
+	if p.keepDays > 0 && p.now.Sub(file.date) < time.Duration(p.keepDays)*24*time.Hour {
+		return true
+	}
+	return false
+}
+
+// bucketed returns the newest file of each daily, weekly, monthly and yearly bucket up to the configured number of
+// buckets, the group has to be sorted newest first
+func (p retentionPolicy) bucketed(group []retentionFile) []retentionFile {
+	var kept []retentionFile
+
+	buckets := []struct {
+		count int
+		key   func(time.Time) string
+	}{
+		{p.daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{p.weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return strconv.Itoa(year) + "-W" + strconv.Itoa(week)
+		}},
+		{p.monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{p.yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	for _, bucket := range buckets {
+		last := ""
+		count := 0
+		for _, file := range group {
+			if count >= bucket.count {
+				break
+			}
+			if key := bucket.key(file.date); key != last {
+				kept = append(kept, file)
+				last = key
+				count++
+			}
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helper function to create files with a modification time in the past
+func createTestFileAge(t *testing.T, file string, age time.Duration) {
+	createTestFile(t, file)
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time of %q: %v", file, err)
+	}
+}
+
+func TestRetentionPolicyKeep(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	var files []string
+	for i, name := range []string{"dump-1", "dump-2", "dump-3", "dump-4"} {
+		file := filepath.Join(testDir, "dst", name)
+		createTestFileAge(t, file, time.Duration(4-i)*24*time.Hour+12*time.Hour)
+		files = append(files, file)
+	}
+	// files in another directory are rotated independently
+	other := filepath.Join(testDir, "src", "dump-1")
+	createTestFileAge(t, other, 10*24*time.Hour)
+	files = append(files, other)
+
+	policy, _ := cleanupRule{Keep: 2}.retention()
+	obsolete := policy.apply(files)
+	if len(obsolete) != 2 || obsolete[0] != files[0] || obsolete[1] != files[1] {
+		t.Errorf("failed - got %q but expected the two oldest files", obsolete)
+	}
+
+	// keep files of the last 3 days
+	policy, _ = cleanupRule{KeepDays: 3}.retention()
+	obsolete = policy.apply(files)
+	if len(obsolete) != 3 || obsolete[0] != files[0] || obsolete[1] != files[1] || obsolete[2] != other {
+		t.Errorf("failed - got %q but expected files older than 3 days", obsolete)
+	}
+
+	// a file is kept if either condition is fulfilled
+	policy, _ = cleanupRule{Keep: 1, KeepDays: 2}.retention()
+	if obsolete = policy.apply(files); len(obsolete) != 3 || obsolete[2] != files[2] {
+		t.Errorf("failed - got %q but expected %d files", obsolete, 3)
+	}
+
+	// without retention all files are obsolete
+	policy, _ = cleanupRule{}.retention()
+	if obsolete = policy.apply(files); len(obsolete) != len(files) {
+		t.Errorf("failed - got %q but expected all files", obsolete)
+	}
+}
+
+func TestRetentionPolicyInvalid(t *testing.T) {
+	if _, err := (cleanupRule{Keep: -1}).retention(); err == nil {
+		t.Errorf("failed - expected error for negative keep")
+	}
+	if _, err := (cleanupRule{KeepDays: -1}).retention(); err == nil {
+		t.Errorf("failed - expected error for negative keepDays")
+	}
+}
+
+func TestRetentionPolicyBuckets(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// one dump per day from 2025-12-01 until 2026-10-17
+	var files []string
+	for date := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.Local); !date.After(time.Date(2026, time.October, 17, 0, 0, 0, 0, time.Local)); date = date.AddDate(0, 0, 1) {
+		file := filepath.Join(testDir, "dst", "db-"+date.Format("2006-01-02")+".sql.gz")
+		createTestFile(t, file)
+		files = append(files, file)
+	}
+
+	rule := cleanupRule{Retention: retentionBuckets{Daily: 3, Weekly: 2, Monthly: 3, Yearly: 2, DatePattern: `db-(\d{4}-\d{2}-\d{2})`}}
+	policy, err := rule.retention()
+	if err != nil {
+		t.Errorf("error - parsing retention: %v", err)
+	}
+
+	obsolete := policy.apply(files)
+	kept := map[string]bool{}
+	for _, file := range files {
+		kept[filepath.Base(file)] = true
+	}
+	for _, file := range obsolete {
+		delete(kept, filepath.Base(file))
+	}
+
+	expected := []string{
+		// daily
+		"db-2026-10-17.sql.gz", "db-2026-10-16.sql.gz", "db-2026-10-15.sql.gz",
+		// weekly, 2026-10-17 is a Saturday and the previous week ends on Sunday 2026-10-11
+		"db-2026-10-11.sql.gz",
+		// monthly
+		"db-2026-09-30.sql.gz", "db-2026-08-31.sql.gz",
+		// yearly
+		"db-2025-12-31.sql.gz",
+	}
+	if len(kept) != len(expected) {
+		t.Errorf("failed - got %d kept files %v but expected %d", len(kept), kept, len(expected))
+	}
+	for _, name := range expected {
+		if !kept[name] {
+			t.Errorf("failed - %q should be kept", name)
+		}
+	}
+}
+
+func TestRetentionPolicyDatePattern(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	dated := filepath.Join(testDir, "dst", "db-20261017.sql")
+	undated := filepath.Join(testDir, "dst", "db-latest.sql")
+	older := filepath.Join(testDir, "dst", "db-20261016.sql")
+	for _, file := range []string{dated, undated, older} {
+		createTestFile(t, file)
+	}
+
+	rule := cleanupRule{Retention: retentionBuckets{Daily: 1, DatePattern: `\d{8}`, DateLayout: "20060102"}}
+	policy, err := rule.retention()
+	if err != nil {
+		t.Errorf("error - parsing retention: %v", err)
+	}
+
+	// files without a date in their name are never cleaned up
+	obsolete := policy.apply([]string{dated, undated, older})
+	if len(obsolete) != 1 || obsolete[0] != older {
+		t.Errorf("failed - got %q but expected only %q", obsolete, older)
+	}
+
+	if _, err := (cleanupRule{Retention: retentionBuckets{DatePattern: "("}}).retention(); err == nil {
+		t.Errorf("failed - expected error for invalid date pattern")
+	}
+	if _, err := (cleanupRule{Retention: retentionBuckets{Weekly: -1}}).retention(); err == nil {
+		t.Errorf("failed - expected error for negative bucket count")
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// rule types which may be used in the order of a run
+const (
+	ruleTypeRelocate = "relocate"
+	ruleTypeCleanup  = "cleanup"
+)
+
+// runStep is a single rule of a run, exactly one of both rules is set
+type runStep struct {
+	relocate *relocateRule
+	cleanup  *cleanupRule
+}
+
+// Run applies all relocate and cleanup rules in the configured order, sharing a single scan per source directory and
+// skipping files already handled by an earlier rule
+func Run(dryRun bool) (Result, error) {
+	var result Result
+
+	steps, err := runOrder(CurrentConfiguration.Defaults.Order)
+	if err != nil {
+		return result, err
+	}
+
+	// log the used configuration file
+	log.Info("use config file: ", viper.ConfigFileUsed())
+
+	executor := newExecutor(dryRun)
+	executor.scans = newScanCache()
+	for _, step := range steps {
+		var rule RuleResult
+		if step.relocate != nil {
+			rule = relocateItem(*step.relocate, executor)
+		} else {
+			rule = cleanupItem(*step.cleanup, executor)
+		}
+		executor.scans.update(rule)
+		result.Rules = append(result.Rules, rule)
+	}
+
+	return result, nil
+}
+
+// runOrder sorts all rules by the configured order, where each value is either a rule type adding all of its remaining
+// rules or the name of a rule, rules not mentioned run afterwards with relocate rules first
+func runOrder(order []string) ([]runStep, error) {
+	var steps []runStep
+	relocateUsed := make([]bool, len(CurrentConfiguration.Relocate))
+	cleanupUsed := make([]bool, len(CurrentConfiguration.Cleanup))
+
+	addRelocate := func(name string) bool {
+		found := false
+		for i := range CurrentConfiguration.Relocate {
+			if !relocateUsed[i] && (name == "" || CurrentConfiguration.Relocate[i].Name == name) {
+				relocateUsed[i] = true
+				steps = append(steps, runStep{relocate: &CurrentConfiguration.Relocate[i]})
+				found = true
+			}
+		}
+		return found
+	}
+	addCleanup := func(name string) bool {
+		found := false
+		for i := range CurrentConfiguration.Cleanup {
+			if !cleanupUsed[i] && (name == "" || CurrentConfiguration.Cleanup[i].Name == name) {
+				cleanupUsed[i] = true
+				steps = append(steps, runStep{cleanup: &CurrentConfiguration.Cleanup[i]})
+				found = true
+			}
+		}
+		return found
+	}
+
+	for _, value := range order {
+		switch value {
+		case ruleTypeRelocate:
+			addRelocate("")
+		case ruleTypeCleanup:
+			addCleanup("")
+		default:
+			relocateFound := addRelocate(value)
+			if cleanupFound := addCleanup(value); !relocateFound && !cleanupFound {
+				return nil, fmt.Errorf("unknown rule in order: %q", value)
+			}
+		}
+	}
+
+	addRelocate("")
+	addCleanup("")
+	return steps, nil
+}
+
+// scannedPath is a path found while scanning a source directory
+type scannedPath struct {
+	path string
+	rel  string
+	info os.FileInfo
+}
+
+// scanCache holds a single scan per source directory and all paths already claimed by a rule
+type scanCache struct {
+	directories map[string][]scannedPath
+	claimed     map[string]bool
+}
+
+func newScanCache() *scanCache {
+	return &scanCache{
+		directories: map[string][]scannedPath{},
+		claimed:     map[string]bool{},
+	}
+}
+
+// files returns all files of the directory matched by the filter which are not claimed by an earlier rule
+func (c *scanCache) files(directory string, filter fileFilter) []string {
+	directory = filepath.Clean(directory)
+
+	entries, ok := c.directories[directory]
+	if !ok {
+		var err error
+		if entries, err = scanDirectory(directory); err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+			}).Error("error reading directory")
+			return nil
+		}
+		c.directories[directory] = entries
+	}
+
+	var files []string
+	skipPrefix := ""
+	for _, entry := range entries {
+		// a walk lists the content of a directory right after the directory itself
+		if skipPrefix != "" && strings.HasPrefix(entry.rel, skipPrefix) {
+			continue
+		}
+		skipPrefix = ""
+
+		if c.claimed[entry.path] {
+			log.WithFields(log.Fields{
+				"file": entry.path,
+			}).Debug("skip claimed file")
+			if entry.info.IsDir() {
+				skipPrefix = entry.rel + "/"
+			}
+			continue
+		}
+
+		matched, descend := matchEntry(filter, entry.path, entry.rel, entry.info)
+		if matched {
+			files = append(files, entry.path)
+		}
+		if !descend {
+			skipPrefix = entry.rel + "/"
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"files": files,
+	}).Debug("found files")
+
+	return files
+}
+
+// update claims all paths a rule acted on and drops the scans of directories the rule wrote files into
+func (c *scanCache) update(result RuleResult) {
+	for _, record := range result.Records {
+		if record.Outcome == OutcomeSkipped {
+			continue
+		}
+		c.claimed[record.Src] = true
+
+		if record.Dst != "" && record.Outcome == OutcomeSuccess {
+			for directory := range c.directories {
+				if isWithinDirectory(record.Dst, directory) {
+					delete(c.directories, directory)
+				}
+			}
+		}
+	}
+}
+
+// scanDirectory lists all paths below directory except temporary and internal files of brot
+func scanDirectory(directory string) ([]scannedPath, error) {
+	var entries []scannedPath
+	ignored := newIgnoredPaths(directory)
+
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("skip reading directory")
+			return nil
+		}
+
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." || isTempFile(info.Name()) {
+			return nil
+		}
+
+		// files brot keeps for itself are never matched
+		if ignored.contains(rel, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		entries = append(entries, scannedPath{path: path, rel: rel, info: info})
+		return nil
+	})
+
+	return entries, err
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunSharedSource(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt", "file_2.txt"})
+	setupCleanupConfig(srcDir, []string{"*.txt"})
+	CurrentConfiguration.Defaults.Order = nil
+
+	// files moved by relocate are never seen by cleanup, even in a dry run
+	for _, dryRun := range []bool{true, false} {
+		result, err := Run(dryRun)
+		if err != nil {
+			t.Fatalf("failed - unexpected error: %v", err)
+		}
+		if len(result.Rules) != 2 || result.Rules[0].Name != "test-move" || result.Rules[1].Matched != 1 || result.ExitCode() != ExitSuccess {
+			t.Errorf("failed - unexpected result: %+v", result)
+		}
+	}
+
+	for i := 1; i <= 2; i++ {
+		file := filepath.Join(dstDir, "file_"+string(rune(48+i))+".txt")
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("failed - file should have been moved: %q", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "file_3.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed - file should have been removed: %q", "file_3.txt")
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"*.txt"})
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Defaults.Order = []string{"cleanup"}
+	defer func() { CurrentConfiguration.Defaults.Order = nil }()
+
+	result, err := Run(false)
+	if err != nil {
+		t.Fatalf("failed - unexpected error: %v", err)
+	}
+	if len(result.Rules) != 2 || result.Rules[0].Removed != 1 || result.Rules[1].Moved != 2 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "file_1.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed - removed file should not have been moved")
+	}
+
+	// rules are addressed by name, unknown names are rejected
+	CurrentConfiguration.Defaults.Order = []string{"test-cleanup", "missing"}
+	if _, err := Run(true); err == nil {
+		t.Errorf("failed - expected error for unknown rule in order")
+	}
+}
+
+func TestRunDestinationBelowSource(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	archiveDir := filepath.Join(srcDir, "archive")
+	createTestDir(t, archiveDir)
+
+	setupRelocateConfig(srcDir, archiveDir, "copy", []string{"file_1.txt"})
+	setupCleanupConfig(srcDir, []string{"file_1.txt"})
+	CurrentConfiguration.Defaults.Order = nil
+
+	// the copy written by relocate is found by a new scan of the source directory
+	result, err := Run(false)
+	if err != nil {
+		t.Fatalf("failed - unexpected error: %v", err)
+	}
+	if len(result.Rules) != 2 || result.Rules[1].Removed != 1 {
+		t.Errorf("failed - unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(archiveDir, "file_1.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed - copied file should have been removed")
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "file_1.txt")); err != nil {
+		t.Errorf("failed - source file should still exist")
+	}
+}
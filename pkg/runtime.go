@@ -16,11 +16,15 @@ limitations under the License.
 package pkg
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -33,31 +37,78 @@ const VersionMajor = 1
 type configuration struct {
 	ApiVersion string `mapstructure:"apiVersion"`
 	Defaults   struct {
-		Loglevel  string `mapstructure:"loglevel"`
-		Logformat string `mapstructure:"logformat"`
+		Loglevel   string   `mapstructure:"loglevel"`
+		Logformat  string   `mapstructure:"logformat"`
+		Quarantine string   `mapstructure:"quarantine"`
+		Journal    string   `mapstructure:"journal"`
+		Order      []string `mapstructure:"order"`
 	} `mapstructure:"defaults"`
-	Relocate []struct {
-		Name        string   `mapstructure:"name"`
-		Source      string   `mapstructure:"src"`
-		Destination string   `mapstructure:"dst"`
-		Patterns    []string `mapstructure:"patterns"`
-		Mode        string   `mapstructure:"mode"`
-	} `mapstructure:"relocate"`
-	Cleanup []struct {
-		Name     string   `mapstructure:"name"`
-		Source   string   `mapstructure:"src"`
-		Patterns []string `mapstructure:"patterns"`
-	} `mapstructure:"cleanup"`
+	Relocate []relocateRule `mapstructure:"relocate"`
+	Cleanup  []cleanupRule  `mapstructure:"cleanup"`
+}
+
+// struct representing a single relocate rule
+type relocateRule struct {
+	Name              string `mapstructure:"name"`
+	Source            string `mapstructure:"src"`
+	Destination       string `mapstructure:"dst"`
+	ruleFilter        `mapstructure:",squash"`
+	Mode              string   `mapstructure:"mode"`
+	OnConflict        string   `mapstructure:"onConflict"`
+	PreserveTree      bool     `mapstructure:"preserveTree"`
+	CreateDestination bool     `mapstructure:"createDestination"`
+	DirectoryMode     string   `mapstructure:"directoryMode"`
+	Preserve          []string `mapstructure:"preserve"`
+	Verify            string   `mapstructure:"verify"`
+}
+
+// struct representing a single cleanup rule
+type cleanupRule struct {
+	Name            string `mapstructure:"name"`
+	Source          string `mapstructure:"src"`
+	ruleFilter      `mapstructure:",squash"`
+	Mode            string           `mapstructure:"mode"`
+	Keep            int              `mapstructure:"keep"`
+	KeepDays        int              `mapstructure:"keepDays"`
+	Retention       retentionBuckets `mapstructure:"retention"`
+	MaxTotalSize    string           `mapstructure:"maxTotalSize"`
+	MinFreeSpace    string           `mapstructure:"minFreeSpace"`
+	RemoveEmptyDirs bool             `mapstructure:"removeEmptyDirs"`
+	PruneEmptyDirs  bool             `mapstructure:"pruneEmptyDirs"`
+}
+
+// options applied when copying or moving a file
+type transferOptions struct {
+	// replace an existing destination file instead of failing
+	Overwrite bool
+	// metadata copied from the source file
+	Preserve preserveOptions
+	// checksum algorithm to compare source and copy, empty disables verification
+	Verify string
 }
 
 var CurrentConfiguration configuration
 
+// rename is replaceable to simulate moves across filesystems in tests
+var rename = os.Rename
+
 var Verbosity int
 
 func FilesFromDirectory(directory string, patterns []string) []string {
+	filter, err := ruleFilter{Patterns: patterns}.compile()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("error in matching pattern")
+	}
+
+	return filesFromDirectory(directory, filter)
+}
+
+func filesFromDirectory(directory string, filter fileFilter) []string {
 	var files []string
 
-	if err := filepath.Walk(directory, visit(patterns, &files)); err != nil {
+	if err := filepath.Walk(directory, visit(directory, filter, &files)); err != nil {
 		log.WithFields(log.Fields{
 			"err": err,
 		}).Error("error reading directory")
@@ -73,14 +124,26 @@ func FilesFromDirectory(directory string, patterns []string) []string {
 }
 
 func FileCopy(src string, dst string) (err error) {
+	return fileCopy(src, dst, transferOptions{})
+}
+
+func fileCopy(src string, dst string, options transferOptions) (err error) {
 
 	// abort when source file is missing
-	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+	srcInfo, err := os.Stat(src)
+	if errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("source file not found: %w", err)
+	} else if err != nil {
+		return err
+	}
+
+	// copy directories recursively
+	if srcInfo.IsDir() {
+		return directoryCopy(src, dst, options)
 	}
 
 	// abort if a file with the same name exists in the destination
-	if _, err := os.Stat(dst); err == nil {
+	if _, err := os.Stat(dst); err == nil && !options.Overwrite {
 		return fmt.Errorf("destination file already exists: %s", dst)
 	}
 
@@ -92,43 +155,180 @@ func FileCopy(src string, dst string) (err error) {
 		_ = in.Close()
 	}(in)
 
-	out, err := os.Create(filepath.Clean(dst))
+	// write into a hidden temporary file next to the destination first
+	out, err := createTempFile(filepath.Clean(dst))
 	if err != nil {
 		return err
 	}
 	defer func(out *os.File) {
 		_ = out.Close()
+		// remove the temporary file unless it was renamed successfully
+		if err != nil {
+			_ = os.Remove(out.Name())
+		}
 	}(out)
 
-	_, err = io.Copy(out, in)
+	// calculate the checksum of the source while copying
+	var reader io.Reader = in
+	var srcHash hash.Hash
+	if options.Verify != "" {
+		if srcHash, err = newHash(options.Verify); err != nil {
+			return err
+		}
+		reader = io.TeeReader(in, srcHash)
+	}
+
+	_, err = io.Copy(out, reader)
 	if err != nil {
 		return err
 	}
-	return out.Close()
+
+	// flush the content to disk before reporting success
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	// read the copy back and compare it with the source
+	if srcHash != nil {
+		var dstHash []byte
+		if dstHash, err = fileHash(out.Name(), options.Verify); err != nil {
+			return err
+		}
+		if !bytes.Equal(srcHash.Sum(nil), dstHash) {
+			err = fmt.Errorf("checksum mismatch after copying %s to %s", src, dst)
+			return err
+		}
+	}
+
+	// copy metadata of the source file, failures do not invalidate the copied content
+	if srcInfo, err := in.Stat(); err == nil {
+		if err := preserveMetadata(src, out.Name(), srcInfo, options.Preserve); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dst":   dst,
+			}).Warn("error preserving metadata")
+		}
+	}
+
+	// move the complete file into place
+	err = os.Rename(out.Name(), filepath.Clean(dst))
+	return err
 }
 
 func FileMove(src string, dst string) (err error) {
-	if _, err := os.Stat(dst); err == nil {
+	return fileMove(src, dst, transferOptions{})
+}
+
+func fileMove(src string, dst string, options transferOptions) (err error) {
+	dstInfo, err := os.Stat(dst)
+	if err == nil && !options.Overwrite {
 		// destination exists, return error
 		return fmt.Errorf("destination file already exists: %s", dst)
-	} else if !errors.Is(err, os.ErrNotExist) {
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
 		// some other stat error occurred
 		return err
 	}
-	// destination does not exist, safe to rename
-	return os.Rename(src, dst)
+
+	// a directory cannot be renamed onto an existing directory, move the existing one aside until the move succeeded
+	if dstInfo != nil && dstInfo.IsDir() {
+		if srcInfo, statErr := os.Stat(src); statErr == nil && srcInfo.IsDir() {
+			aside := tempName(dst)
+			if err := os.Rename(dst, aside); err != nil {
+				return err
+			}
+			defer func() {
+				err = replaceDirectory(dst, aside, err)
+			}()
+		}
+	}
+	// destination does not exist or may be replaced, safe to rename
+	err = rename(src, dst)
+	if errors.Is(err, syscall.EXDEV) {
+		// source and destination are on different filesystems
+		return moveAcrossFilesystems(src, dst, options)
+	}
+	return err
+}
+
+// replaceDirectory removes the directory moved aside from dst once the move succeeded and moves it back otherwise
+func replaceDirectory(dst string, aside string, err error) error {
+	if err != nil {
+		// a failed move leaves nothing behind at dst unless the source could not be removed after copying it
+		if _, statErr := os.Lstat(dst); errors.Is(statErr, os.ErrNotExist) {
+			if restoreErr := os.Rename(aside, dst); restoreErr != nil {
+				return errors.Join(err, restoreErr)
+			}
+			return err
+		}
+	}
+
+	if removeErr := os.RemoveAll(aside); removeErr != nil {
+		log.WithFields(log.Fields{
+			"error": removeErr,
+			"dir":   aside,
+		}).Warn("error removing replaced directory")
+	}
+	return err
+}
+
+// moveAcrossFilesystems copies the file and removes the source only after the copy is verified
+func moveAcrossFilesystems(src string, dst string, options transferOptions) (err error) {
+	log.WithFields(log.Fields{
+		"src": src,
+		"dst": dst,
+	}).Debug("fall back to copy and remove")
+
+	// a moved file keeps its permissions and timestamps, ownership and extended attributes only if requested as many
+	// filesystems cannot store them
+	options.Preserve.Mode = true
+	options.Preserve.Timestamps = true
+
+	// the source is removed afterwards, so always compare checksums of both copies
+	if options.Verify == "" {
+		options.Verify = verifySHA256
+	}
+
+	if err := fileCopy(src, dst, options); err != nil {
+		return err
+	}
+
+	// compare the size of both files before touching the source in addition to the checksums compared while copying
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	if pathSize(src, srcInfo) != pathSize(dst, dstInfo) {
+		_ = os.RemoveAll(dst)
+		return fmt.Errorf("size mismatch after copying %s to %s", src, dst)
+	}
+
+	return os.RemoveAll(src)
 }
 
 func FileRemove(src string) (err error) {
-	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+	info, err := os.Lstat(src)
+	if errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("file not found: %w", err)
 	} else if err != nil {
 		return err
 	}
+	// remove directories including their content
+	if info.IsDir() {
+		return os.RemoveAll(src)
+	}
 	return os.Remove(src)
 }
 
-func visit(patterns []string, files *[]string) filepath.WalkFunc {
+func visit(directory string, filter fileFilter, files *[]string) filepath.WalkFunc {
+	ignored := newIgnoredPaths(directory)
+
 	return func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -137,31 +337,110 @@ func visit(patterns []string, files *[]string) filepath.WalkFunc {
 			return nil
 		}
 
-		// iterate over all patterns
-		for _, pattern := range patterns {
+		// path relative to the source directory used by path patterns
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
 
-			// skip if pattern is empty and do not match any files
-			if pattern == "" {
-				continue
-			}
+		// the source directory itself and temporary files of brot are never a match
+		if rel == "." || isTempFile(info.Name()) {
+			return nil
+		}
 
-			matched, err := filepath.Match(pattern, info.Name())
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error": err,
-				}).Fatal("error in matching pattern")
-			}
-			if matched {
-				*files = append(*files, path)
-
-				log.WithFields(log.Fields{
-					"file": path,
-				}).Debug("matched file")
-				// only process one pattern match per file
-				break
+		// neither are files brot keeps for itself, e.g. quarantined files
+		if ignored.contains(rel, info) {
+			log.WithFields(log.Fields{
+				"file": path,
+			}).Debug("skip internal file")
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
+		matched, descend := matchEntry(filter, path, rel, info)
+		if matched {
+			*files = append(*files, path)
+		}
+		if !descend {
+			return filepath.SkipDir
+		}
 		return nil
 	}
 }
+
+// ignoredPaths holds the files and directories of brot itself and the trash which rules never match
+type ignoredPaths struct {
+	// absolute source directory the relative paths are resolved against
+	root  string
+	paths []string
+}
+
+func newIgnoredPaths(directory string) ignoredPaths {
+	root, err := filepath.Abs(directory)
+	if err != nil {
+		root = directory
+	}
+	return ignoredPaths{
+		root:  root,
+		paths: []string{QuarantineDirectory(), homeTrashDirectory(), JournalFile()},
+	}
+}
+
+// contains reports whether the path relative to the source directory belongs to brot or is a trash directory
+func (p ignoredPaths) contains(rel string, info os.FileInfo) bool {
+	// trash directories at the top of other volumes
+	if info.IsDir() && (info.Name() == ".Trash" || strings.HasPrefix(info.Name(), ".Trash-")) {
+		return true
+	}
+
+	path := filepath.Join(p.root, filepath.FromSlash(rel))
+	for _, ignored := range p.paths {
+		if isWithinDirectory(path, ignored) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEntry checks a single path found below the source directory, descend reports whether the walk has to continue
+// below a directory
+func matchEntry(filter fileFilter, path string, rel string, info os.FileInfo) (matched bool, descend bool) {
+	// do not descend into directories which are too deep to contain any matches
+	depth := strings.Count(rel, "/")
+	descend = !info.IsDir() || filter.descend(depth)
+
+	// check type, depth and all patterns
+	if !filter.matchType(info) || !filter.matchDepth(depth) || !filter.included(info.Name(), rel) {
+		return false, descend
+	}
+
+	// skip files matching any of the exclude patterns
+	if filter.excluded(info.Name(), rel) {
+		log.WithFields(log.Fields{
+			"file": path,
+		}).Debug("skip excluded file")
+		return false, descend
+	}
+
+	// skip files not fulfilling the age and size conditions, directories are only summed up if needed
+	size := info.Size()
+	if info.IsDir() && filter.sized() {
+		size = pathSize(path, info)
+	}
+	if !filter.matchInfo(info, size) {
+		log.WithFields(log.Fields{
+			"file": path,
+		}).Debug("skip filtered file")
+		return false, descend
+	}
+
+	log.WithFields(log.Fields{
+		"file": path,
+	}).Debug("matched file")
+
+	// a matched directory is handled as a whole
+	return true, descend && !info.IsDir()
+}
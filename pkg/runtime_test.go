@@ -16,12 +16,30 @@ limitations under the License.
 package pkg
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
+// keep the journal of all tests out of the user's state directory
+func TestMain(m *testing.M) {
+	stateHome, err := os.MkdirTemp("", "brot-state-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("XDG_STATE_HOME", stateHome)
+
+	code := m.Run()
+	_ = os.RemoveAll(stateHome)
+	os.Exit(code)
+}
+
 // helper function to create files with dummy content
 func createTestFile(t *testing.T, file string) {
 	err := os.WriteFile(file, []byte("TESTDATA"), 0644)
@@ -167,3 +185,88 @@ func TestFileRemove(t *testing.T) {
 		t.Errorf("failed - file was not deleted: %q", file)
 	}
 }
+
+func TestConfigurationUnmarshal(t *testing.T) {
+	yaml := []byte(`
+apiVersion: v1
+relocate:
+  - name: archive logs
+    src: /src
+    dst: /dst
+    patterns:
+      - "*.log"
+    olderThan: 14d
+    mode: move
+cleanup:
+  - name: big files
+    src: /src
+    patterns:
+      - "*.tmp"
+    minSize: 1MiB
+`)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(yaml)); err != nil {
+		t.Errorf("error - reading configuration: %v", err)
+	}
+
+	var conf configuration
+	if err := v.Unmarshal(&conf); err != nil {
+		t.Errorf("error - parsing configuration: %v", err)
+	}
+
+	if len(conf.Relocate) != 1 || conf.Relocate[0].Patterns[0] != "*.log" || conf.Relocate[0].OlderThan != "14d" {
+		t.Errorf("failed - relocate rule not parsed: %+v", conf.Relocate)
+	}
+	if len(conf.Cleanup) != 1 || conf.Cleanup[0].Patterns[0] != "*.tmp" || conf.Cleanup[0].MinSize != "1MiB" {
+		t.Errorf("failed - cleanup rule not parsed: %+v", conf.Cleanup)
+	}
+}
+
+func TestFileMoveAcrossFilesystems(t *testing.T) {
+	// create a temporary directory with test data and remove everything afterward again
+	testDir := initTestDirectory(t)
+	defer func() {
+		if err := os.RemoveAll(testDir); err != nil {
+			t.Errorf("error - removing test directory at: %q", testDir)
+		}
+	}()
+
+	// simulate source and destination on different filesystems
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() {
+		rename = os.Rename
+	}()
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+
+	modTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	err := FileMove(src, dst)
+	if err != nil {
+		t.Errorf("error - moving %q to %q: %v", src, dst, err)
+	}
+
+	// the moved file keeps its modification time
+	if info, err := os.Stat(dst); err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("failed - modification time not preserved: %q", dst)
+	}
+
+	// check if source file is absent
+	if _, err := os.Stat(src); err == nil {
+		t.Errorf("failed - source was not removed: %q", src)
+	}
+
+	// check if destination file is present with the same content
+	content, err := os.ReadFile(dst)
+	if err != nil || string(content) != "TESTDATA" {
+		t.Errorf("failed - destination file missing or corrupted: %q", dst)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// name parts of temporary files written while copying
+const (
+	tempFilePrefix = ".brot-"
+	tempFileSuffix = ".tmp"
+)
+
+// maximum number of bytes of the final file name kept in the name of a temporary file, long names would otherwise
+// exceed the file name limit of the filesystem
+const tempFileBaseLength = 64
+
+// temporary files which were not modified for this long are left over from an interrupted run
+const staleTempFileAge = time.Hour
+
+// tempName returns a random name for a hidden temporary file in the directory of path
+func tempName(path string) string {
+	dir, base := filepath.Split(path)
+	return filepath.Join(dir, tempFilePrefix+tempFileBase(base)+"-"+strconv.FormatUint(rand.Uint64(), 36)+tempFileSuffix)
+}
+
+// createTempFile creates a new hidden file in the directory of path which is later renamed to path
+func createTempFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(tempName(path), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return file, err
+	}
+}
+
+// createTempDirectory creates a new hidden directory in the directory of path which is later renamed to path
+func createTempDirectory(path string) (string, error) {
+	dir, base := filepath.Split(path)
+	return os.MkdirTemp(dir, tempFilePrefix+tempFileBase(base)+"-*"+tempFileSuffix)
+}
+
+// tempFileBase shortens a file name to be used within the name of a temporary file without splitting characters
+func tempFileBase(base string) string {
+	if len(base) <= tempFileBaseLength {
+		return base
+	}
+	base = base[:tempFileBaseLength]
+	for !utf8.ValidString(base) {
+		base = base[:len(base)-1]
+	}
+	return base
+}
+
+// isTempFile reports whether name is a temporary file created by brot
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, tempFilePrefix) && strings.HasSuffix(name, tempFileSuffix)
+}
+
+// removeStaleTempFiles deletes temporary files left behind in directory by an interrupted copy
+func removeStaleTempFiles(directory string) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !isTempFile(entry.Name()) {
+			continue
+		}
+
+		// files still being written by another brot process are recent
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < staleTempFileAge {
+			continue
+		}
+
+		path := filepath.Join(directory, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"file":  path,
+			}).Warn("error removing stale temporary file")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"file": path,
+		}).Info("remove stale temporary file")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCreateTempFile(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+
+	file, err := createTempFile(dst)
+	if err != nil {
+		t.Errorf("error - creating temporary file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	if filepath.Dir(file.Name()) != filepath.Dir(dst) {
+		t.Errorf("failed - temporary file %q not created next to %q", file.Name(), dst)
+	}
+	if !isTempFile(filepath.Base(file.Name())) {
+		t.Errorf("failed - %q not recognized as temporary file", file.Name())
+	}
+	if isTempFile("test_1.txt") || isTempFile(".brot-test_1.txt") {
+		t.Errorf("failed - regular file recognized as temporary file")
+	}
+}
+
+func TestFileCopyLongName(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// the longest name most filesystems allow
+	name := strings.Repeat("ä", 125) + ".txt"
+	src := filepath.Join(testDir, "src", name)
+	dst := filepath.Join(testDir, "dst", name)
+	createTestFile(t, src)
+
+	if err := FileCopy(src, dst); err != nil {
+		t.Errorf("error - copying file with long name: %v", err)
+	}
+	if base := tempFileBase(name); len(base) > tempFileBaseLength || !utf8.ValidString(base) {
+		t.Errorf("failed - invalid temporary name %q", base)
+	}
+}
+
+func TestRemoveStaleTempFiles(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	dstDir := filepath.Join(testDir, "dst")
+	stale := filepath.Join(dstDir, ".brot-test_1.txt-abc.tmp")
+	recent := filepath.Join(dstDir, ".brot-test_2.txt-def.tmp")
+	createTestFile(t, stale)
+	createTestFile(t, recent)
+
+	old := time.Now().Add(-2 * staleTempFileAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Errorf("error - setting modification time: %v", err)
+	}
+
+	removeStaleTempFiles(dstDir)
+
+	if _, err := os.Stat(stale); err == nil {
+		t.Errorf("failed - stale temporary file should be removed: %q", stale)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("failed - recent temporary file should still exist: %q", recent)
+	}
+}
+
+func TestFileCopyAtomic(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	dstDir := filepath.Join(testDir, "dst")
+
+	// a successful copy leaves nothing but the destination file
+	if err := FileCopy(filepath.Join(testDir, "src", "test_1.txt"), filepath.Join(dstDir, "test_1.txt")); err != nil {
+		t.Errorf("error - copying file: %v", err)
+	}
+
+	// a failing copy (unknown checksum algorithm) leaves nothing behind
+	options := transferOptions{Verify: "md5"}
+	if err := fileCopy(filepath.Join(testDir, "src", "test_2.txt"), filepath.Join(dstDir, "broken.txt"), options); err == nil {
+		t.Errorf("failed - copying with an unknown checksum algorithm should fail")
+	}
+
+	entries, err := os.ReadDir(dstDir)
+	if err != nil {
+		t.Errorf("error - reading directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "test_1.txt" {
+		t.Errorf("failed - unexpected files in destination: %v", entries)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// trashFile moves a file into the trash as specified by freedesktop.org and returns its new location
+//
+// See https://specifications.freedesktop.org/trash-spec/latest/ for details.
+func trashFile(src string) (string, error) {
+	path, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return "", fmt.Errorf("file not found: %w", err)
+	}
+
+	trashDirectory, topDirectory := findTrashDirectory(path)
+
+	filesDirectory := filepath.Join(trashDirectory, "files")
+	infoDirectory := filepath.Join(trashDirectory, "info")
+	for _, directory := range []string{filesDirectory, infoDirectory} {
+		if err := os.MkdirAll(directory, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	// paths in a trash on another volume are stored relative to the volume's top directory
+	originalPath := path
+	if topDirectory != "" {
+		if rel, err := filepath.Rel(topDirectory, path); err == nil {
+			originalPath = rel
+		}
+	}
+	trashInfo := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: filepath.ToSlash(originalPath)}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"))
+
+	// reserve a unique name by creating the info file first
+	name, infoPath, err := createTrashInfo(infoDirectory, filepath.Base(path), trashInfo)
+	if err != nil {
+		return "", err
+	}
+
+	trashedPath := filepath.Join(filesDirectory, name)
+	if err := fileMove(path, trashedPath, transferOptions{}); err != nil {
+		_ = os.Remove(infoPath)
+		return "", err
+	}
+
+	return trashedPath, nil
+}
+
+// restoreTrashedFile moves a file from the trash back to its original path and removes its info file
+func restoreTrashedFile(trashedPath string, original string) error {
+	if err := os.MkdirAll(filepath.Dir(original), defaultDirMode); err != nil {
+		return err
+	}
+	if err := fileMove(trashedPath, original, transferOptions{}); err != nil {
+		return err
+	}
+
+	trashDirectory := filepath.Dir(filepath.Dir(trashedPath))
+	return os.Remove(filepath.Join(trashDirectory, "info", filepath.Base(trashedPath)+".trashinfo"))
+}
+
+// createTrashInfo writes the info file for the first free name derived from base
+func createTrashInfo(infoDirectory string, base string, content string) (string, string, error) {
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+
+		infoPath := filepath.Join(infoDirectory, name+".trashinfo")
+		file, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		} else if err != nil {
+			return "", "", err
+		}
+
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(infoPath)
+			return "", "", err
+		}
+
+		return name, infoPath, nil
+	}
+}
+
+// homeTrashDirectory returns the trash directory of the current user
+func homeTrashDirectory() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash")
+}
+
+// findTrashDirectory returns the trash directory to use for path and the top directory of its volume if the trash
+// is not the user's home trash
+func findTrashDirectory(path string) (string, string) {
+	homeTrash := homeTrashDirectory()
+
+	// files on the same volume as the home trash can be renamed into it
+	if sameDevice(path, existingParent(homeTrash)) {
+		return homeTrash, ""
+	}
+
+	topDirectory := mountTopDirectory(path)
+	uid := strconv.Itoa(os.Getuid())
+
+	// a shared trash prepared by an administrator has to be a sticky directory and no symbolic link
+	if info, err := os.Lstat(filepath.Join(topDirectory, ".Trash")); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		trashDirectory := filepath.Join(topDirectory, ".Trash", uid)
+		if err := os.MkdirAll(trashDirectory, 0700); err == nil {
+			return trashDirectory, topDirectory
+		}
+	}
+
+	// otherwise use a trash per user on the volume
+	trashDirectory := filepath.Join(topDirectory, ".Trash-"+uid)
+	if err := os.MkdirAll(trashDirectory, 0700); err == nil {
+		return trashDirectory, topDirectory
+	}
+
+	// fall back to copying the file into the home trash
+	return homeTrash, ""
+}
+
+// sameDevice reports whether both paths are located on the same device
+func sameDevice(path string, other string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Lstat(other)
+	if err != nil {
+		return false
+	}
+
+	device, ok := deviceID(info)
+	otherDevice, otherOk := deviceID(otherInfo)
+	if !ok || !otherOk {
+		// without device information assume a single volume
+		return true
+	}
+	return device == otherDevice
+}
+
+// existingParent returns path or its nearest parent directory which exists
+func existingParent(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// mountTopDirectory returns the topmost parent directory of path on the same device, i.e. its mount point
+func mountTopDirectory(path string) string {
+	top := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(top)
+		if parent == top || !sameDevice(top, parent) {
+			return top
+		}
+		top = parent
+	}
+}
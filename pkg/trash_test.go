@@ -0,0 +1,120 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrashFile(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	// use a home trash within the test directory
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testDir, "share"))
+	trashDir := filepath.Join(testDir, "share", "Trash")
+
+	src := filepath.Join(testDir, "src", "test 1%.txt")
+	createTestFile(t, src)
+
+	trashed, err := trashFile(src)
+	if err != nil {
+		t.Errorf("error - trashing file: %v", err)
+		return
+	}
+
+	// check if the file was moved into the trash
+	if _, err := os.Stat(src); err == nil {
+		t.Errorf("failed - source file should not exist: %q", src)
+	}
+	if trashed != filepath.Join(trashDir, "files", "test 1%.txt") {
+		t.Errorf("failed - file trashed to unexpected location: %q", trashed)
+	}
+
+	// check the info file
+	info, err := os.ReadFile(filepath.Join(trashDir, "info", "test 1%.txt.trashinfo"))
+	if err != nil {
+		t.Errorf("error - reading trash info: %v", err)
+	}
+	lines := strings.Split(string(info), "\n")
+	if lines[0] != "[Trash Info]" {
+		t.Errorf("failed - unexpected trash info header: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "Path=/") || !strings.HasSuffix(lines[1], "/src/test%201%25.txt") {
+		t.Errorf("failed - unexpected trash info path: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "DeletionDate=") || len(lines[2]) != len("DeletionDate=2006-01-02T15:04:05") {
+		t.Errorf("failed - unexpected trash info date: %q", lines[2])
+	}
+}
+
+func TestTrashFileNameCollision(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testDir, "share"))
+	trashDir := filepath.Join(testDir, "share", "Trash")
+
+	// trash two files with the same name
+	first := filepath.Join(testDir, "src", "test_1.txt")
+	second := filepath.Join(testDir, "dst", "test_1.txt")
+	createTestFile(t, second)
+
+	if _, err := trashFile(first); err != nil {
+		t.Errorf("error - trashing file: %v", err)
+	}
+	trashed, err := trashFile(second)
+	if err != nil {
+		t.Errorf("error - trashing file: %v", err)
+	}
+
+	if trashed != filepath.Join(trashDir, "files", "test_1.txt.2") {
+		t.Errorf("failed - file trashed to unexpected location: %q", trashed)
+	}
+	if _, err := os.Stat(filepath.Join(trashDir, "info", "test_1.txt.2.trashinfo")); err != nil {
+		t.Errorf("failed - trash info of second file missing")
+	}
+}
+
+func TestTrashFileMissing(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testDir, "share"))
+
+	if _, err := trashFile(filepath.Join(testDir, "src", "missing.txt")); err == nil {
+		t.Errorf("failed - expected error for missing file")
+	}
+}
+
+func TestMountTopDirectory(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	file := filepath.Join(testDir, "src", "test_1.txt")
+	top := mountTopDirectory(file)
+
+	// the top directory is a parent on the same device
+	if !strings.HasPrefix(file, top) {
+		t.Errorf("failed - %q is not a parent of %q", top, file)
+	}
+	if !sameDevice(file, top) {
+		t.Errorf("failed - %q is not on the same device as %q", top, file)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// checksum algorithms to verify copied files
+const (
+	verifySHA256 = "sha256"
+	verifyCRC64  = "crc64"
+)
+
+// isVerifyAlgorithm reports whether algorithm is a supported checksum; empty disables verification
+func isVerifyAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case "", verifySHA256, verifyCRC64:
+		return true
+	}
+	return false
+}
+
+// newHash returns a new hash for the given checksum algorithm
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case verifySHA256:
+		return sha256.New(), nil
+	case verifyCRC64:
+		return crc64.New(crc64.MakeTable(crc64.ECMA)), nil
+	}
+	return nil, fmt.Errorf("invalid checksum algorithm %q", algorithm)
+}
+
+// fileHash calculates the checksum of a file's content
+func fileHash(path string, algorithm string) ([]byte, error) {
+	hash, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
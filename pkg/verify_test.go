@@ -0,0 +1,86 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileHash(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	file1 := filepath.Join(testDir, "src", "test_1.txt")
+	file2 := filepath.Join(testDir, "src", "test_2.txt")
+	other := filepath.Join(testDir, "src", "other.txt")
+	createTestFileContent(t, other, "OTHERDATA")
+
+	for _, algorithm := range []string{verifySHA256, verifyCRC64} {
+		hash1, err1 := fileHash(file1, algorithm)
+		hash2, err2 := fileHash(file2, algorithm)
+		hash3, err3 := fileHash(other, algorithm)
+		if err1 != nil || err2 != nil || err3 != nil {
+			t.Errorf("error - hashing files with %q: %v, %v, %v", algorithm, err1, err2, err3)
+		}
+		if !bytes.Equal(hash1, hash2) {
+			t.Errorf("failed - %q checksums of identical files differ", algorithm)
+		}
+		if bytes.Equal(hash1, hash3) {
+			t.Errorf("failed - %q checksums of different files are equal", algorithm)
+		}
+	}
+
+	if _, err := fileHash(file1, "md5"); err == nil {
+		t.Errorf("failed - expected error for unknown algorithm")
+	}
+}
+
+func TestIsVerifyAlgorithm(t *testing.T) {
+	if !isVerifyAlgorithm("") || !isVerifyAlgorithm(verifySHA256) || !isVerifyAlgorithm(verifyCRC64) {
+		t.Errorf("failed - known algorithm rejected")
+	}
+	if isVerifyAlgorithm("md5") {
+		t.Errorf("failed - unknown algorithm accepted")
+	}
+}
+
+func TestFileCopyVerify(t *testing.T) {
+	testDir := initTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	src := filepath.Join(testDir, "src", "test_1.txt")
+	dst := filepath.Join(testDir, "dst", "test_1.txt")
+
+	if err := fileCopy(src, dst, transferOptions{Verify: verifySHA256}); err != nil {
+		t.Errorf("error - copying %q to %q: %v", src, dst, err)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("failed - destination file missing: %q", dst)
+	}
+
+	// an unknown algorithm fails before anything is written
+	broken := filepath.Join(testDir, "dst", "broken.txt")
+	if err := fileCopy(src, broken, transferOptions{Verify: "md5"}); err == nil {
+		t.Errorf("failed - expected error for unknown algorithm")
+	}
+	entries, _ := os.ReadDir(filepath.Join(testDir, "dst"))
+	if len(entries) != 1 {
+		t.Errorf("failed - unexpected files in destination: %v", entries)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Watch applies all relocate and cleanup rules once and then keeps running, re-applying
+// a rule whenever files in its source directory change. Events are debounced by delay so
+// that a burst of writes (e.g. a running download) only triggers a single pass.
+func Watch(ctx context.Context, dryRun bool, delay time.Duration) error {
+	// log the used configuration file
+	log.Info("use config file: ", viper.ConfigFileUsed())
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func(watcher *fsnotify.Watcher) {
+		_ = watcher.Close()
+	}(watcher)
+
+	// register all source directories including their subdirectories
+	for _, item := range CurrentConfiguration.Relocate {
+		watchDirectory(watcher, os.ExpandEnv(item.Source))
+	}
+	for _, item := range CurrentConfiguration.Cleanup {
+		watchDirectory(watcher, os.ExpandEnv(item.Source))
+	}
+
+	// handle everything which is already present before waiting for events
+	logWatchPass(Relocate(dryRun))
+	logWatchPass(Cleanup(dryRun))
+
+	pendingRelocate := map[int]bool{}
+	pendingCleanup := map[int]bool{}
+
+	timer := time.NewTimer(delay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stop watching")
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("error watching directory")
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			log.WithFields(log.Fields{
+				"event": event.Op.String(),
+				"file":  event.Name,
+			}).Debug("received event")
+
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			// follow newly created subdirectories
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					watchDirectory(watcher, event.Name)
+				}
+			}
+
+			// remember every rule which is responsible for the changed path
+			for i, item := range CurrentConfiguration.Relocate {
+				if isWithinDirectory(event.Name, os.ExpandEnv(item.Source)) {
+					pendingRelocate[i] = true
+				}
+			}
+			for i, item := range CurrentConfiguration.Cleanup {
+				if isWithinDirectory(event.Name, os.ExpandEnv(item.Source)) {
+					pendingCleanup[i] = true
+				}
+			}
+
+			timer.Reset(delay)
+
+		case <-timer.C:
+			// apply the pending rules in the order of the configuration
+			var result Result
+			executor := newExecutor(dryRun)
+			for i, item := range CurrentConfiguration.Relocate {
+				if pendingRelocate[i] {
+					result.Rules = append(result.Rules, relocateItem(item, executor))
+				}
+			}
+			for i, item := range CurrentConfiguration.Cleanup {
+				if pendingCleanup[i] {
+					result.Rules = append(result.Rules, cleanupItem(item, executor))
+				}
+			}
+			logWatchPass(result)
+
+			clear(pendingRelocate)
+			clear(pendingCleanup)
+		}
+	}
+}
+
+// logWatchPass logs all errors and a summary of a single pass as there is no exit code while watching
+func logWatchPass(result Result) {
+	var summary RuleResult
+	for _, rule := range result.Rules {
+		summary.Matched += rule.Matched
+		summary.Moved += rule.Moved
+		summary.Copied += rule.Copied
+		summary.Removed += rule.Removed
+		summary.Skipped += rule.Skipped
+		summary.Failed += rule.Failed
+	}
+
+	for _, err := range result.Errors() {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("error applying rule")
+	}
+
+	fields := log.Fields{
+		"rules":   len(result.Rules),
+		"matched": summary.Matched,
+		"moved":   summary.Moved,
+		"copied":  summary.Copied,
+		"removed": summary.Removed,
+		"skipped": summary.Skipped,
+		"failed":  summary.Failed,
+	}
+	if result.ExitCode() != ExitSuccess {
+		log.WithFields(fields).Warn("finish pass with errors")
+		return
+	}
+	log.WithFields(fields).Info("finish pass")
+}
+
+// watchDirectory adds the directory and all of its subdirectories to the watcher
+func watchDirectory(watcher *fsnotify.Watcher, directory string) {
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Warn("skip watching directory")
+			return nil
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if err := watcher.Add(path); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"dir":   path,
+			}).Warn("skip watching directory")
+			return nil
+		}
+
+		log.WithFields(log.Fields{
+			"dir": path,
+		}).Debug("watch directory")
+
+		return nil
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("error reading directory")
+	}
+}
+
+// isWithinDirectory reports whether path is the directory itself or located below it
+func isWithinDirectory(path string, directory string) bool {
+	rel, err := filepath.Rel(filepath.Clean(directory), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
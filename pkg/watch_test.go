@@ -0,0 +1,182 @@
+/*
+Copyright © 2021-2026 Siwei Luo <siwei@lu0.org>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// helper function to wait until a file shows up or the timeout is reached
+func waitForFile(file string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func startTestWatch(t *testing.T) (context.CancelFunc, chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, false, 50*time.Millisecond)
+	}()
+	return cancel, done
+}
+
+func stopTestWatch(t *testing.T, cancel context.CancelFunc, done chan error) {
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("error - watch returned: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("failed - watch did not stop after cancellation")
+	}
+}
+
+func TestWatchInitialPass(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"file_1.txt"})
+	CurrentConfiguration.Cleanup = nil
+
+	cancel, done := startTestWatch(t)
+	defer stopTestWatch(t, cancel, done)
+
+	// verify files already present are relocated on startup
+	dstFile := filepath.Join(dstDir, "file_1.txt")
+	if !waitForFile(dstFile, 2*time.Second) {
+		t.Errorf("failed - destination file missing: %q", dstFile)
+	}
+}
+
+func TestWatchNewFile(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	dstDir := filepath.Join(testDir, "dst")
+
+	setupRelocateConfig(srcDir, dstDir, "move", []string{"*.pdf"})
+	CurrentConfiguration.Cleanup = nil
+
+	cancel, done := startTestWatch(t)
+	defer stopTestWatch(t, cancel, done)
+
+	// give the watcher some time to register the source directory
+	time.Sleep(100 * time.Millisecond)
+
+	// create a file in a subdirectory created after the watch started
+	createTestDir(t, filepath.Join(srcDir, "sub"))
+	time.Sleep(100 * time.Millisecond)
+	createTestFile(t, filepath.Join(srcDir, "sub", "new.pdf"))
+
+	dstFile := filepath.Join(dstDir, "new.pdf")
+	if !waitForFile(dstFile, 2*time.Second) {
+		t.Errorf("failed - new file was not relocated: %q", dstFile)
+	}
+}
+
+func TestWatchCleanup(t *testing.T) {
+	testDir := initCleanupTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+
+	setupCleanupConfig(srcDir, []string{"*.tmp"})
+	CurrentConfiguration.Relocate = nil
+
+	cancel, done := startTestWatch(t)
+	defer stopTestWatch(t, cancel, done)
+
+	time.Sleep(100 * time.Millisecond)
+
+	file := filepath.Join(srcDir, "obsolete.tmp")
+	createTestFile(t, file)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("failed - new file was not removed: %q", file)
+}
+
+func TestIsWithinDirectory(t *testing.T) {
+	if !isWithinDirectory("/tmp/src/a/b.txt", "/tmp/src") {
+		t.Errorf("failed - nested file should be within directory")
+	}
+	if !isWithinDirectory("/tmp/src", "/tmp/src/") {
+		t.Errorf("failed - directory should be within itself")
+	}
+	if isWithinDirectory("/tmp/srcfoo/b.txt", "/tmp/src") {
+		t.Errorf("failed - sibling directory should not be within directory")
+	}
+	if isWithinDirectory("/tmp/b.txt", "/tmp/src") {
+		t.Errorf("failed - parent directory should not be within directory")
+	}
+}
+
+func TestWatchRuleOrder(t *testing.T) {
+	testDir := initRelocateTestDirectory(t)
+	defer os.RemoveAll(testDir)
+
+	srcDir := filepath.Join(testDir, "src")
+	firstDir := filepath.Join(testDir, "dst")
+	secondDir := filepath.Join(testDir, "second")
+	createTestDir(t, secondDir)
+
+	// both rules match the same files, the first one always wins
+	setupRelocateConfig(srcDir, firstDir, "move", []string{"*.pdf"})
+	CurrentConfiguration.Relocate = append(CurrentConfiguration.Relocate, relocateRule{
+		Name:        "test-second",
+		Source:      srcDir,
+		Destination: secondDir,
+		ruleFilter:  ruleFilter{Patterns: []string{"*.pdf"}},
+		Mode:        relocateMove,
+	})
+	CurrentConfiguration.Cleanup = nil
+
+	cancel, done := startTestWatch(t)
+	defer stopTestWatch(t, cancel, done)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// every pass applies the rules in the same order
+	for i := 1; i <= 4; i++ {
+		name := "new_" + strconv.Itoa(i) + ".pdf"
+		createTestFile(t, filepath.Join(srcDir, name))
+		if !waitForFile(filepath.Join(firstDir, name), 2*time.Second) {
+			t.Errorf("failed - file was not relocated by the first rule: %q", name)
+		}
+	}
+}